@@ -2,12 +2,13 @@ package cmd
 
 import (
 	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"net"
 	"os"
-	"os/exec"
-	"path"
 	"sort"
 	"strconv"
 	"strings"
@@ -20,6 +21,7 @@ import (
 
 	"github.com/fatih/color"
 	"github.com/fi-ts/cloud-go/api/client/cluster"
+	"github.com/go-openapi/strfmt"
 	"github.com/gosimple/slug"
 	"github.com/metal-stack/metal-lib/auth"
 
@@ -32,6 +34,7 @@ import (
 	"github.com/Masterminds/semver/v3"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
 )
 
 type auditConfigOptionsMap map[string]struct {
@@ -121,6 +124,34 @@ func newClusterCmd(c *config) *cobra.Command {
 		ValidArgsFunction: c.comp.ClusterListCompletion,
 		PreRun:            bindPFlags,
 	}
+	clusterEstimateCmd := &cobra.Command{
+		Use:   "estimate <clusterid>",
+		Short: "estimate the monthly costs of a cluster",
+		Long:  "estimates the expected monthly costs of a cluster from the minimum and maximum size of its worker groups, its firewall and static egress ips.",
+		Example: `The prices are taken from the price table in the config file:
+
+		~/.cloudctl/config.yaml
+		---
+		costs-cpu-hour: 0.01          # Costs in Euro per CPU Hour
+		costs-memory-gi-hour: 0.005   # Costs in Euro per Gi Memory Hour
+		costs-storage-gi-hour: 0.0001 # Costs in Euro per Gi Storage Hour
+		costs-ip-hour: 0.005          # Costs in Euro per static IP Hour
+		costs-machine-types:
+		  c1-xlarge-x86:              # priced by its size
+		    cpu: 32
+		    memory: 128
+		    storage: 960
+		  s3-large-x86:               # priced by a fixed price in Euro per Hour
+		    hour: 1.5
+
+		cloudctl cluster estimate <clusterid>
+		`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return c.clusterEstimate(args)
+		},
+		ValidArgsFunction: c.comp.ClusterListCompletion,
+		PreRun:            bindPFlags,
+	}
 	clusterKubeconfigCmd := &cobra.Command{
 		Use:   "kubeconfig <clusterid>",
 		Short: "get cluster kubeconfig",
@@ -130,9 +161,21 @@ func newClusterCmd(c *config) *cobra.Command {
 		ValidArgsFunction: c.comp.ClusterListCompletion,
 		PreRun:            bindPFlags,
 	}
+	clusterKubeconfigPruneCmd := &cobra.Command{
+		Use:   "prune",
+		Short: "remove contexts of clusters that do not exist anymore from the kubeconfig",
+		Long: `removes contexts and their cluster entries from the kubeconfig which were merged with "cluster kubeconfig --merge" for clusters that do not exist anymore.
+only contexts merged by cloudctl from the current cloudctl context are considered, all other entries are left untouched.
+contexts merged by older versions of cloudctl are not marked with the cloud context and cluster id. they are recognized by their name, which is the cluster name,
+and their api server api.<cluster name>.<project>.<domain> in the domain of the existing clusters, and removed if no existing cluster has this api server.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return c.clusterKubeconfigPrune()
+		},
+		PreRun: bindPFlags,
+	}
 
 	clusterReconcileCmd := &cobra.Command{
-		Use:   "reconcile <clusterid>",
+		Use:   "reconcile [<clusterid>]",
 		Short: "trigger cluster reconciliation",
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return c.reconcileCluster(args)
@@ -141,7 +184,7 @@ func newClusterCmd(c *config) *cobra.Command {
 		PreRun:            bindPFlags,
 	}
 	clusterUpdateCmd := &cobra.Command{
-		Use:   "update <clusterid>",
+		Use:   "update [<clusterid>]",
 		Short: "update a cluster",
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return c.updateCluster(args)
@@ -185,12 +228,38 @@ func newClusterCmd(c *config) *cobra.Command {
 	clusterMachineSSHCmd := &cobra.Command{
 		Use:   "ssh <clusterid>",
 		Short: "ssh access a machine/firewall of the cluster",
+		Long:  "ssh access a machine/firewall of the cluster. firewalls are accessed directly, worker machines are accessed through a firewall of the cluster, which connects to them from its tenant vrf. this requires the metal user of the firewall to be allowed to run \"sudo ip vrf exec\".",
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return c.clusterMachineSSH(args, false)
 		},
 		ValidArgsFunction: c.comp.ClusterListCompletion,
 		PreRun:            bindPFlags,
 	}
+	clusterMachinePortForwardCmd := &cobra.Command{
+		Use:   "port-forward <clusterid>",
+		Short: "forward local ports through a firewall of the cluster",
+		Long:  "forwards local ports through a firewall of the cluster to hosts reachable from the firewall, e.g. node exporters or the kubelet of the workers. ips in the private network of the cluster are connected from the tenant vrf of the firewall, which requires the metal user of the firewall to be allowed to run \"sudo ip vrf exec\", other hosts from its default vrf. the forwardings are active until interrupted.",
+		Example: `forward the node exporter and the kubelet of a worker:
+cloudctl cluster machine port-forward <clusterid> --machineid <firewallid> -L 9100:10.0.0.5:9100 -L 10250:10.0.0.5:10250
+
+forward to an ipv6 address:
+cloudctl cluster machine port-forward <clusterid> --machineid <firewallid> -L 9100:[2001:db8::5]:9100`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return c.clusterMachinePortForward(args)
+		},
+		ValidArgsFunction: c.comp.ClusterListCompletion,
+		PreRun:            bindPFlags,
+	}
+	clusterSSHConfigCmd := &cobra.Command{
+		Use:   "ssh-config <clusterid>",
+		Short: "generate an ssh config to access the firewalls and workers of the cluster with ssh",
+		Long:  "writes the ssh keypair of the cluster and an ssh config with a host entry for every firewall and worker into a directory of the cluster. the hosts are named <cluster>-fw-<last part of the machine id> and <cluster>-worker-<last part of the machine id>, so they do not change when other machines are added or removed. workers are reached with a ProxyCommand which connects to them from the tenant vrf of a firewall, like cluster machine ssh does. run the command again when machines of the cluster changed to regenerate the config.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return c.clusterSSHConfig(args)
+		},
+		ValidArgsFunction: c.comp.ClusterListCompletion,
+		PreRun:            bindPFlags,
+	}
 	clusterMachineConsoleCmd := &cobra.Command{
 		Use:   "console <clusterid>",
 		Short: "console access a machine/firewall of the cluster",
@@ -203,6 +272,7 @@ func newClusterCmd(c *config) *cobra.Command {
 	clusterMachineResetCmd := &cobra.Command{
 		Use:   "reset <clusterid>",
 		Short: "hard power reset of a machine/firewall of the cluster",
+		Long:  "hard power reset of a machine/firewall of the cluster. with --all the workers of the cluster are reset in a rolling manner, the next batch is only reset when the machines of the batch are back, the nodes are ready and the system components are healthy again. the rolling reset is aborted on the first failure.",
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return c.clusterMachineReset(args)
 		},
@@ -212,6 +282,7 @@ func newClusterCmd(c *config) *cobra.Command {
 	clusterMachineCycleCmd := &cobra.Command{
 		Use:   "cycle <clusterid>",
 		Short: "soft power cycle of a machine/firewall of the cluster",
+		Long:  "soft power cycle of a machine/firewall of the cluster. with --all the workers of the cluster are cycled in a rolling manner, the next batch is only cycled when the machines of the batch are back, the nodes are ready and the system components are healthy again. the rolling cycle is aborted on the first failure.",
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return c.clusterMachineCycle(args)
 		},
@@ -230,12 +301,50 @@ func newClusterCmd(c *config) *cobra.Command {
 	clusterLogsCmd := &cobra.Command{
 		Use:   "logs <clusterid>",
 		Short: "get logs for the cluster",
+		Long:  "get logs for the cluster, with --follow the cluster is polled and condition transitions, errors and progress changes are printed until interrupted, with -o json every event is printed as a single line.",
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return c.clusterLogs(args)
 		},
 		ValidArgsFunction: c.comp.ClusterListCompletion,
 		PreRun:            bindPFlags,
 	}
+	clusterApplyCmd := &cobra.Command{
+		Use:   "apply",
+		Short: "create/update a cluster",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return c.clusterApply()
+		},
+		PreRun: bindPFlags,
+	}
+	clusterEditCmd := &cobra.Command{
+		Use:   "edit <clusterid>",
+		Short: "edit a cluster",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return c.clusterEdit(args)
+		},
+		ValidArgsFunction: c.comp.ClusterListCompletion,
+		PreRun:            bindPFlags,
+	}
+	clusterExportCmd := &cobra.Command{
+		Use:   "export [<clusterid>]",
+		Short: "export the specification of clusters as yaml",
+		Long:  "exports the user defined specification of a cluster or all clusters of a project as yaml documents which can be applied again with \"cloudctl cluster apply -f\". status, machines and server-generated fields are left out.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return c.clusterExport(args)
+		},
+		ValidArgsFunction: c.comp.ClusterListCompletion,
+		PreRun:            bindPFlags,
+	}
+	clusterWaitCmd := &cobra.Command{
+		Use:   "wait <clusterid>",
+		Short: "wait until the cluster reached the given state",
+		Long:  "polls the cluster until the last operation succeeded, the cluster is healthy or deleted. exits with an error if the operation failed or the timeout is reached.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return c.clusterWait(args)
+		},
+		ValidArgsFunction: c.comp.ClusterListCompletion,
+		PreRun:            bindPFlags,
+	}
 	clusterSplunkConfigManifestCmd := &cobra.Command{
 		Use:   "splunk-config-manifest",
 		Short: "create a manifest for a custom splunk configuration, every provided provided overrides the default setting",
@@ -245,10 +354,15 @@ func newClusterCmd(c *config) *cobra.Command {
 		PreRun: bindPFlags,
 	}
 
+	clusterCmd.PersistentFlags().String("project", "", "project of the cluster, narrows down the clusters a cluster name or id prefix given as argument is resolved to.")
+	clusterCmd.PersistentFlags().String("tenant", "", "tenant of the cluster, narrows down the clusters a cluster name or id prefix given as argument is resolved to.")
+	must(clusterCmd.RegisterFlagCompletionFunc("project", c.comp.ProjectListCompletion))
+	must(clusterCmd.RegisterFlagCompletionFunc("tenant", c.comp.TenantListCompletion))
+
 	clusterCreateCmd.Flags().String("name", "", "name of the cluster, max 10 characters. [required]")
 	clusterCreateCmd.Flags().String("description", "", "description of the cluster. [optional]")
 	clusterCreateCmd.Flags().String("project", "", "project where this cluster should belong to. [required]")
-	clusterCreateCmd.Flags().String("partition", "", "partition of the cluster. [required]")
+	clusterCreateCmd.Flags().String("partition", "", "partition of the cluster. [required, unless --from is given]")
 	clusterCreateCmd.Flags().String("seed", "", "name of seed where this cluster should be scheduled. [optional]")
 	clusterCreateCmd.Flags().String("purpose", "evaluation", fmt.Sprintf("purpose of the cluster, can be one of %s. SLA is only given on production clusters. [optional]", strings.Join(completion.ClusterPurposes, "|")))
 	clusterCreateCmd.Flags().String("version", "", "kubernetes version of the cluster. defaults to latest available, check cluster inputs for possible values. [optional]")
@@ -270,12 +384,18 @@ func newClusterCmd(c *config) *cobra.Command {
 	clusterCreateCmd.Flags().Duration("healthtimeout", 0, "period (e.g. \"24h\") after which an unhealthy node is declared failed and will be replaced. [optional]")
 	clusterCreateCmd.Flags().Duration("draintimeout", 0, "period (e.g. \"3h\") after which a draining node will be forcefully deleted. [optional]")
 	clusterCreateCmd.Flags().BoolP("reversed-vpn", "", false, "enables usage of reversed-vpn instead of konnectivity tunnel for worker connectivity. [optional]")
+	clusterCreateCmd.Flags().String("maintenance-begin", "", "begin of the daily maintenance time window in the form <hh:mm> [<timezone>], e.g. \"02:00 Europe/Berlin\", defaults to 22:00 UTC+1. [optional]")
+	clusterCreateCmd.Flags().String("maintenance-end", "", "end of the daily maintenance time window in the form <hh:mm> [<timezone>], e.g. \"03:30 Europe/Berlin\", defaults to 23:30 UTC+1. [optional]")
+	clusterCreateCmd.Flags().String("from", "", "id of an existing cluster to take partition, version, worker groups, firewall, external networks, audit, purpose and labels from, flags given explicitly override the settings of this cluster. egress ips are not taken over. [optional]")
+	clusterCreateCmd.Flags().Bool("wait", false, "wait until the cluster creation has succeeded. [optional]")
+	clusterCreateCmd.Flags().Bool("estimate", false, "only print the expected monthly costs of the cluster from the price table in the config file instead of creating it, see cluster estimate --help. [optional]")
+	clusterCreateCmd.Flags().Duration("timeout", clusterWaitTimeoutDefault, "maximum time to wait when --wait is given. [optional]")
 
 	must(clusterCreateCmd.MarkFlagRequired("name"))
 	must(clusterCreateCmd.MarkFlagRequired("project"))
-	must(clusterCreateCmd.MarkFlagRequired("partition"))
 	must(clusterCreateCmd.RegisterFlagCompletionFunc("project", c.comp.ProjectListCompletion))
 	must(clusterCreateCmd.RegisterFlagCompletionFunc("partition", c.comp.PartitionListCompletion))
+	must(clusterCreateCmd.RegisterFlagCompletionFunc("from", c.comp.ClusterListCompletion))
 	must(clusterCreateCmd.RegisterFlagCompletionFunc("seed", c.comp.PartitionListCompletion))
 	must(clusterCreateCmd.RegisterFlagCompletionFunc("external-networks", c.comp.NetworkListCompletion))
 	must(clusterCreateCmd.RegisterFlagCompletionFunc("version", c.comp.VersionListCompletion))
@@ -295,6 +415,25 @@ func newClusterCmd(c *config) *cobra.Command {
 
 	clusterDescribeCmd.Flags().Bool("no-machines", false, "does not return in the output")
 
+	clusterApplyCmd.Flags().StringP("file", "f", "", `filename of the create or update request in yaml format, or - for stdin.
+	A cluster is identified by its project and name, clusters which do not exist are created, existing clusters are updated.
+	Fields which are omitted are left untouched on update, e.g. an empty list of egressrules removes all egress rules whereas
+	omitting egressrules keeps the current ones.
+	Example cluster update:
+
+	# cloudctl cluster export cluster1 > cluster1.yaml
+	# vi cluster1.yaml
+	## either via stdin
+	# cat cluster1.yaml | cloudctl cluster apply -f -
+	## or via file
+	# cloudctl cluster apply -f cluster1.yaml
+	`)
+	must(clusterApplyCmd.MarkFlagRequired("file"))
+
+	clusterExportCmd.Flags().String("project", "", "export all clusters of the given project.")
+	clusterExportCmd.Flags().StringP("file", "f", "", "filename to write the yaml documents to, defaults to stdout.")
+	must(clusterExportCmd.RegisterFlagCompletionFunc("project", c.comp.ProjectListCompletion))
+
 	// Cluster list --------------------------------------------------------------------
 	clusterListCmd.Flags().String("id", "", "show clusters of given id")
 	clusterListCmd.Flags().String("name", "", "show clusters of given name")
@@ -334,6 +473,13 @@ func newClusterCmd(c *config) *cobra.Command {
 	clusterUpdateCmd.Flags().BoolP("autoupdate-kubernetes", "", false, "enables automatic updates of the kubernetes patch version of the cluster")
 	clusterUpdateCmd.Flags().BoolP("autoupdate-machineimages", "", false, "enables automatic updates of the worker node images of the cluster, be aware that this deletes worker nodes!")
 	clusterUpdateCmd.Flags().BoolP("reversed-vpn", "", false, "enables usage of reversed-vpn instead of konnectivity tunnel for worker connectivity.")
+	clusterUpdateCmd.Flags().String("maintenance-begin", "", "begin of the daily maintenance time window in the form <hh:mm> [<timezone>], e.g. \"02:00 Europe/Berlin\".")
+	clusterUpdateCmd.Flags().String("maintenance-end", "", "end of the daily maintenance time window in the form <hh:mm> [<timezone>], e.g. \"03:30 Europe/Berlin\".")
+	clusterUpdateCmd.Flags().Bool("wait", false, "wait until the cluster update has succeeded.")
+	clusterUpdateCmd.Flags().StringSlice("selector", []string{}, "update all clusters with the given labels instead of a single cluster, e.g. --selector team=x,stage=dev.")
+	clusterUpdateCmd.Flags().Int("parallelism", clusterBulkParallelismDefault, "number of clusters to update at once when --selector is given.")
+	clusterUpdateCmd.Flags().Bool("dry-run", false, "print the update request and the resulting changes of the cluster without sending it.")
+	clusterUpdateCmd.Flags().Duration("timeout", clusterWaitTimeoutDefault, "maximum time to wait when --wait is given.")
 
 	must(clusterUpdateCmd.RegisterFlagCompletionFunc("version", c.comp.VersionListCompletion))
 	must(clusterUpdateCmd.RegisterFlagCompletionFunc("firewalltype", c.comp.FirewallTypeListCompletion))
@@ -347,7 +493,7 @@ func newClusterCmd(c *config) *cobra.Command {
 			cobra.ShellCompDirectiveNoFileComp
 	}))
 
-	clusterInputsCmd.Flags().String("partition", "", "partition of the constraints.")
+	clusterInputsCmd.Flags().StringSlice("partition", []string{}, "partition of the constraints, if given multiple times the constraints of the partitions are compared side by side.")
 	must(clusterInputsCmd.RegisterFlagCompletionFunc("partition", c.comp.PartitionListCompletion))
 
 	// Cluster splunk config manifest --------------------------------------------------------------------
@@ -361,18 +507,39 @@ func newClusterCmd(c *config) *cobra.Command {
 	// Cluster machine ... --------------------------------------------------------------------
 	clusterMachineSSHCmd.Flags().String("machineid", "", "machine to connect to.")
 	must(clusterMachineSSHCmd.MarkFlagRequired("machineid"))
-	must(clusterMachineSSHCmd.RegisterFlagCompletionFunc("machineid", c.comp.ClusterFirewallListCompletion))
+	must(clusterMachineSSHCmd.RegisterFlagCompletionFunc("machineid", c.comp.ClusterMachineListCompletion))
+	clusterMachineSSHCmd.Flags().String("known-hosts", "", "known hosts file to verify the host keys against, defaults to ~/.ssh/known_hosts.")
+	clusterMachineSSHCmd.Flags().Bool("trust-on-first-use", false, "add the host keys of unknown hosts to the known hosts file instead of refusing the connection.")
 
 	clusterMachineConsoleCmd.Flags().String("machineid", "", "machine to connect to.")
 	must(clusterMachineConsoleCmd.MarkFlagRequired("machineid"))
 	must(clusterMachineConsoleCmd.RegisterFlagCompletionFunc("machineid", c.comp.ClusterMachineListCompletion))
+	clusterMachineConsoleCmd.Flags().String("known-hosts", "", "known hosts file to verify the host keys against, defaults to ~/.ssh/known_hosts.")
+	clusterMachineConsoleCmd.Flags().Bool("trust-on-first-use", false, "add the host keys of unknown hosts to the known hosts file instead of refusing the connection.")
+
+	clusterMachinePortForwardCmd.Flags().String("machineid", "", "firewall to forward the ports through.")
+	clusterMachinePortForwardCmd.Flags().StringSliceP("local", "L", []string{}, "port forwarding in the form [<bind address>:]<local port>:<remote host>:<remote port>, can be given multiple times.")
+	clusterMachinePortForwardCmd.Flags().String("known-hosts", "", "known hosts file to verify the host keys against, defaults to ~/.ssh/known_hosts.")
+	clusterMachinePortForwardCmd.Flags().Bool("trust-on-first-use", false, "add the host keys of unknown hosts to the known hosts file instead of refusing the connection.")
+	must(clusterMachinePortForwardCmd.MarkFlagRequired("machineid"))
+	must(clusterMachinePortForwardCmd.MarkFlagRequired("local"))
+	must(clusterMachinePortForwardCmd.RegisterFlagCompletionFunc("machineid", c.comp.ClusterFirewallListCompletion))
+
+	clusterSSHConfigCmd.Flags().String("host-prefix", "", "prefix of the host aliases, defaults to the name of the cluster.")
+	clusterSSHConfigCmd.Flags().String("dir", "", "directory to write the ssh config and keypair of the cluster to, defaults to ~/.cloudctl/ssh.")
 
 	clusterMachineResetCmd.Flags().String("machineid", "", "machine to reset.")
-	must(clusterMachineResetCmd.MarkFlagRequired("machineid"))
+	clusterMachineResetCmd.Flags().Bool("all", false, "reset all workers of the cluster one batch after the other, waiting until the nodes are ready and the system components are healthy again in between.")
+	clusterMachineResetCmd.Flags().String("workergroup", "", "only reset the workers of the given worker group, requires --all.")
+	clusterMachineResetCmd.Flags().Int("parallelism", 1, "number of workers to reset at once, requires --all.")
+	clusterMachineResetCmd.Flags().Duration("timeout", clusterWaitTimeoutDefault, "maximum time to wait for the cluster to become healthy after every batch, requires --all.")
 	must(clusterMachineResetCmd.RegisterFlagCompletionFunc("machineid", c.comp.ClusterMachineListCompletion))
 
-	clusterMachineCycleCmd.Flags().String("machineid", "", "machine to reset.")
-	must(clusterMachineCycleCmd.MarkFlagRequired("machineid"))
+	clusterMachineCycleCmd.Flags().String("machineid", "", "machine to cycle.")
+	clusterMachineCycleCmd.Flags().Bool("all", false, "cycle all workers of the cluster one batch after the other, waiting until the nodes are ready and the system components are healthy again in between.")
+	clusterMachineCycleCmd.Flags().String("workergroup", "", "only cycle the workers of the given worker group, requires --all.")
+	clusterMachineCycleCmd.Flags().Int("parallelism", 1, "number of workers to cycle at once, requires --all.")
+	clusterMachineCycleCmd.Flags().Duration("timeout", clusterWaitTimeoutDefault, "maximum time to wait for the cluster to become healthy after every batch, requires --all.")
 	must(clusterMachineCycleCmd.RegisterFlagCompletionFunc("machineid", c.comp.ClusterMachineListCompletion))
 
 	clusterMachineReinstallCmd.Flags().String("machineid", "", "machine to reinstall.")
@@ -383,12 +550,23 @@ func newClusterCmd(c *config) *cobra.Command {
 	clusterMachineCmd.AddCommand(clusterMachineListCmd)
 	clusterMachineCmd.AddCommand(clusterMachineSSHCmd)
 	clusterMachineCmd.AddCommand(clusterMachineConsoleCmd)
+	clusterMachineCmd.AddCommand(clusterMachinePortForwardCmd)
 	clusterMachineCmd.AddCommand(clusterMachineResetCmd)
 	clusterMachineCmd.AddCommand(clusterMachineCycleCmd)
 	clusterMachineCmd.AddCommand(clusterMachineReinstallCmd)
 
 	clusterReconcileCmd.Flags().Bool("retry", false, "Executes a cluster \"retry\" operation instead of regular \"reconcile\".")
 	clusterReconcileCmd.Flags().Bool("maintain", false, "Executes a cluster \"maintain\" operation instead of regular \"reconcile\".")
+	clusterReconcileCmd.Flags().Bool("wait", false, "wait until the reconciliation has succeeded.")
+	clusterReconcileCmd.Flags().StringSlice("selector", []string{}, "reconcile all clusters with the given labels instead of a single cluster, e.g. --selector team=x,stage=dev.")
+	clusterReconcileCmd.Flags().Int("parallelism", clusterBulkParallelismDefault, "number of clusters to reconcile at once when --selector is given.")
+	clusterReconcileCmd.Flags().Duration("timeout", clusterWaitTimeoutDefault, "maximum time to wait when --wait is given.")
+
+	clusterWaitCmd.Flags().String("for", clusterWaitForSucceeded, fmt.Sprintf("the state to wait for, can be one of %s.", strings.Join(clusterWaitConditions, "|")))
+	clusterWaitCmd.Flags().Duration("timeout", clusterWaitTimeoutDefault, "maximum time to wait.")
+	must(clusterWaitCmd.RegisterFlagCompletionFunc("for", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return clusterWaitConditions, cobra.ShellCompDirectiveNoFileComp
+	}))
 
 	clusterIssuesCmd.Flags().String("id", "", "show clusters of given id")
 	clusterIssuesCmd.Flags().String("name", "", "show clusters of given name")
@@ -396,26 +574,49 @@ func newClusterCmd(c *config) *cobra.Command {
 	clusterIssuesCmd.Flags().String("partition", "", "show clusters in partition")
 	clusterIssuesCmd.Flags().String("tenant", "", "show clusters of given tenant")
 
+	clusterIssuesCmd.Flags().String("fail-on", "", fmt.Sprintf("exit with an error if there are issues of the given severity or higher, can be one of %s. the exit code is 2 for warnings and 3 for critical issues, other errors exit with 1.", strings.Join(output.IssueSeverities, "|")))
+	clusterIssuesCmd.Flags().Bool("structured", false, "print a flat list of issues with type, severity and days remaining instead of the clusters with -o json|yaml.")
+
+	must(clusterIssuesCmd.RegisterFlagCompletionFunc("fail-on", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return output.IssueSeverities, cobra.ShellCompDirectiveNoFileComp
+	}))
 	must(clusterIssuesCmd.RegisterFlagCompletionFunc("name", c.comp.ClusterNameCompletion))
 	must(clusterIssuesCmd.RegisterFlagCompletionFunc("project", c.comp.ProjectListCompletion))
 	must(clusterIssuesCmd.RegisterFlagCompletionFunc("partition", c.comp.PartitionListCompletion))
 	must(clusterIssuesCmd.RegisterFlagCompletionFunc("tenant", c.comp.TenantListCompletion))
 
+	clusterLogsCmd.Flags().BoolP("follow", "f", false, "keep polling the cluster and print new events until interrupted.")
+	clusterLogsCmd.Flags().Duration("interval", clusterWaitPollInterval, "poll interval when following the logs.")
+
 	clusterKubeconfigCmd.Flags().Bool("merge", false, "merges the cluster's kubeconfig into the current active kubeconfig, otherwise an individual kubeconfig is printed to console only")
 	clusterKubeconfigCmd.Flags().Bool("set-context", false, "when setting the merge parameter to true, immediately activates the cluster's context")
+	clusterKubeconfigCmd.Flags().Bool("exec-credential", false, "instead of embedding the current token, the user fetches a token from the cloudctl context with \"cloudctl kubeconfig exec-credential\" on every use, so the kubeconfig keeps working after the token expired.")
+
+	clusterKubeconfigPruneCmd.Flags().Bool("dry-run", false, "only print the contexts that would be removed.")
+	clusterKubeconfigCmd.AddCommand(clusterKubeconfigPruneCmd)
 
 	clusterCmd.AddCommand(clusterCreateCmd)
 	clusterCmd.AddCommand(clusterListCmd)
 	clusterCmd.AddCommand(clusterKubeconfigCmd)
 	clusterCmd.AddCommand(clusterDeleteCmd)
 	clusterCmd.AddCommand(clusterDescribeCmd)
+	clusterCmd.AddCommand(clusterEstimateCmd)
 	clusterCmd.AddCommand(clusterInputsCmd)
 	clusterCmd.AddCommand(clusterReconcileCmd)
 	clusterCmd.AddCommand(clusterUpdateCmd)
 	clusterCmd.AddCommand(clusterMachineCmd)
 	clusterCmd.AddCommand(clusterLogsCmd)
 	clusterCmd.AddCommand(clusterIssuesCmd)
+	clusterCmd.AddCommand(clusterSSHConfigCmd)
 	clusterCmd.AddCommand(clusterSplunkConfigManifestCmd)
+	clusterCmd.AddCommand(clusterWaitCmd)
+	clusterCmd.AddCommand(clusterApplyCmd)
+	clusterCmd.AddCommand(clusterExportCmd)
+	clusterCmd.AddCommand(clusterEditCmd)
+	clusterCmd.AddCommand(newClusterWorkerGroupCmd(c))
+	clusterCmd.AddCommand(newClusterUpgradeCmd(c))
+	clusterCmd.AddCommand(newClusterEgressCmd(c))
+	clusterCmd.AddCommand(newClusterAuditCmd(c))
 
 	return clusterCmd
 }
@@ -451,12 +652,36 @@ func (c *config) clusterCreate() error {
 	// FIXME helper and validation
 	networks := viper.GetStringSlice("external-networks")
 	egress := viper.GetStringSlice("egress")
-	maintenanceBegin := "220000+0100"
-	maintenanceEnd := "233000+0100"
+	version := viper.GetString("version")
+	timeWindow, err := maintenanceTimeWindowFromFlags(viper.GetString("maintenance-begin"), viper.GetString("maintenance-end"), &models.V1MaintenanceTimeWindow{
+		Begin: pointer.StringPtr(maintenanceBeginDefault),
+		End:   pointer.StringPtr(maintenanceEndDefault),
+	}, time.Now())
+	if err != nil {
+		return err
+	}
 
 	reversedVPN := strconv.FormatBool(viper.GetBool("reversed-vpn"))
 
-	version := viper.GetString("version")
+	var source *models.V1ClusterResponse
+	if from := viper.GetString("from"); from != "" {
+		findRequest := cluster.NewFindClusterParams().WithID(from).WithReturnMachines(pointer.BoolPtr(false))
+		resp, err := c.cloud.Cluster.FindCluster(findRequest, nil)
+		if err != nil {
+			return err
+		}
+		source = resp.Payload
+		if !viper.IsSet("partition") {
+			partition = pointer.StringDeref(source.PartitionID, "")
+		}
+		if !viper.IsSet("version") && source.Kubernetes != nil {
+			version = pointer.StringDeref(source.Kubernetes.Version, "")
+		}
+	}
+	if partition == "" {
+		return fmt.Errorf("partition is required, either with --partition or --from")
+	}
+
 	if version == "" {
 		request := cluster.NewListConstraintsParams()
 		constraints, err := c.cloud.Cluster.ListConstraints(request, nil)
@@ -484,16 +709,11 @@ func (c *config) clusterCreate() error {
 		version = sortedVersions[len(sortedVersions)-1].String()
 	}
 
-	machineImage := models.V1MachineImage{}
+	machineImage := &models.V1MachineImage{}
 	if machineImageAndVersion != "" {
-		machineImageParts := strings.Split(machineImageAndVersion, "-")
-		if len(machineImageParts) == 2 {
-			machineImage = models.V1MachineImage{
-				Name:    &machineImageParts[0],
-				Version: &machineImageParts[1],
-			}
-		} else {
-			log.Fatalf("given machineimage:%s is invalid must be in the form <name>-<version>", machineImageAndVersion)
+		machineImage, err = parseMachineImage(machineImageAndVersion)
+		if err != nil {
+			return err
 		}
 	}
 
@@ -528,7 +748,7 @@ func (c *config) clusterCreate() error {
 				MaxSurge:       &maxsurge,
 				MaxUnavailable: &maxunavailable,
 				MachineType:    &machineType,
-				MachineImage:   &machineImage,
+				MachineImage:   machineImage,
 				CRI:            &cri,
 			},
 		},
@@ -541,10 +761,7 @@ func (c *config) clusterCreate() error {
 		},
 		Audit: auditConfig.Config,
 		Maintenance: &models.V1Maintenance{
-			TimeWindow: &models.V1MaintenanceTimeWindow{
-				Begin: &maintenanceBegin,
-				End:   &maintenanceEnd,
-			},
+			TimeWindow: timeWindow,
 		},
 		AdditionalNetworks: networks,
 		PartitionID:        &partition,
@@ -569,13 +786,41 @@ func (c *config) clusterCreate() error {
 		scr.Workers[0].DrainTimeout = int64(draintimeout)
 	}
 
+	if source != nil {
+		applyClusterCreateSource(scr, source, viper.IsSet)
+	}
+
+	if viper.GetBool("estimate") {
+		prices, err := priceTableFromConfig()
+		if err != nil {
+			return err
+		}
+		estimate, err := estimateClusterCosts(prices, name, pointer.StringDeref(scr.FirewallSize, ""), scr.Workers, scr.EgressRules)
+		if err != nil {
+			return err
+		}
+		return output.New().Print(estimate)
+	}
+	err = enforceClusterPolicies(policyClusterFromCreateRequest(scr), nil)
+	if err != nil {
+		return err
+	}
+	err = confirmClusterCreate(scr)
+	if err != nil {
+		return err
+	}
+
 	request := cluster.NewCreateClusterParams()
 	request.SetBody(scr)
 	shoot, err := c.cloud.Cluster.CreateCluster(request, nil)
 	if err != nil {
 		return err
 	}
-	return output.New().Print(shoot.Payload)
+	result, err := c.waitForClusterIfRequested(shoot.Payload, clusterWaitForSucceeded, time.Time{})
+	if err != nil {
+		return err
+	}
+	return output.New().Print(result)
 }
 
 func (c *config) clusterList() error {
@@ -662,8 +907,18 @@ func (c *config) clusterKubeconfig(args []string) error {
 		return fmt.Errorf("active user %s has no oidc authProvider, check config", authContext.User)
 	}
 
+	ctxs, err := api.GetContexts()
+	if err != nil {
+		return err
+	}
+
+	var execUser *helper.ExecCredentialUser
+	if viper.GetBool("exec-credential") {
+		execUser = c.execCredentialUser(ctxs.CurrentContext, kubeconfigFile)
+	}
+
 	if !viper.GetBool("merge") {
-		mergedKubeconfig, err := helper.EnrichKubeconfigTpl(kubeconfigTpl, authContext)
+		mergedKubeconfig, err := helper.EnrichKubeconfigTpl(kubeconfigTpl, authContext, execUser)
 		if err != nil {
 			return err
 		}
@@ -688,7 +943,12 @@ func (c *config) clusterKubeconfig(args []string) error {
 		auth.SetCurrentContext(currentCfg, contextName)
 	}
 
-	mergedKubeconfig, err := helper.MergeKubeconfigTpl(currentCfg, kubeconfigTpl, contextName, *clusterResp.Payload.Name, authContext)
+	extension := helper.KubeconfigExtension{
+		ClusterID:    id,
+		CloudContext: ctxs.CurrentContext,
+	}
+
+	mergedKubeconfig, err := helper.MergeKubeconfigTpl(currentCfg, kubeconfigTpl, contextName, *clusterResp.Payload.Name, authContext, execUser, extension)
 	if err != nil {
 		return err
 	}
@@ -703,6 +963,106 @@ func (c *config) clusterKubeconfig(args []string) error {
 	return nil
 }
 
+// execCredentialUser returns a kubeconfig user which fetches its token from the given cloudctl context
+// with "cloudctl kubeconfig exec-credential".
+func (c *config) execCredentialUser(cloudContext, kubeconfigFile string) *helper.ExecCredentialUser {
+	args := []string{"kubeconfig", "exec-credential"}
+	if cloudContext != "" {
+		args = append(args, "--context", cloudContext)
+	}
+	if kubeconfigFile != "" {
+		args = append(args, "--kubeconfig", kubeconfigFile)
+	}
+	return &helper.ExecCredentialUser{
+		Name:        api.FormatContextName(api.CloudContext, cloudContext) + "-exec",
+		APIVersion:  execCredentialAPIVersion,
+		Command:     c.name,
+		Args:        args,
+		InstallHint: fmt.Sprintf("%s is required to authenticate against this cluster, see https://github.com/fi-ts/cloudctl", c.name),
+	}
+}
+
+func (c *config) clusterKubeconfigPrune() error {
+	ctxs, err := api.GetContexts()
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.cloud.Cluster.ListClusters(cluster.NewListClustersParams().WithReturnMachines(pointer.BoolPtr(false)), nil)
+	if err != nil {
+		return err
+	}
+	existing := helper.ExistingClusters{IDs: map[string]bool{}, Endpoints: map[string]bool{}}
+	for _, s := range resp.Payload {
+		existing.IDs[*s.ID] = true
+		if endpoint := pointer.StringDeref(s.DNSEndpoint, ""); endpoint != "" {
+			existing.Endpoints[helper.KubeconfigServerHost(endpoint)] = true
+		}
+	}
+
+	currentCfg, filename, _, err := auth.LoadKubeConfig(viper.GetString("kubeconfig"))
+	if err != nil {
+		return err
+	}
+
+	pruned, err := helper.PruneKubeconfig(currentCfg, ctxs.CurrentContext, existing)
+	if err != nil {
+		return err
+	}
+	if len(pruned) == 0 {
+		fmt.Printf("no stale contexts found in %s\n", filename)
+		return nil
+	}
+
+	if viper.GetBool("dry-run") {
+		for _, p := range pruned {
+			fmt.Printf("would remove context %q of %s\n", p.Name, prunedContextCluster(p))
+		}
+		return nil
+	}
+
+	prunedKubeconfig, err := yaml.Marshal(currentCfg)
+	if err != nil {
+		return err
+	}
+	err = os.WriteFile(filename, prunedKubeconfig, 0600)
+	if err != nil {
+		return err
+	}
+
+	for _, p := range pruned {
+		fmt.Printf("%s removed context %q of %s from %s\n", color.GreenString("✔"), p.Name, prunedContextCluster(p), filename)
+	}
+	return nil
+}
+
+// prunedContextCluster describes the cluster of a pruned context, contexts merged before cloudctl marked them only
+// contain the api server of the cluster.
+func prunedContextCluster(p helper.PrunedContext) string {
+	if p.ClusterID == "" {
+		return fmt.Sprintf("cluster %s with api server %s (merged by an older cloudctl)", p.ClusterName, p.Server)
+	}
+	return "cluster " + p.ClusterID
+}
+
+// clusterReconcileRequestFromFlags returns the reconcile request with the operation given by flags.
+func clusterReconcileRequestFromFlags() (*models.V1ClusterReconcileRequest, error) {
+	if helper.ViperBool("retry") != nil && helper.ViperBool("maintain") != nil {
+		return nil, fmt.Errorf("--retry and --maintain are mutually exclusive")
+	}
+
+	var operation *string
+	if viper.GetBool("retry") {
+		o := "retry"
+		operation = &o
+	}
+	if viper.GetBool("maintain") {
+		o := "maintain"
+		operation = &o
+	}
+	return &models.V1ClusterReconcileRequest{Operation: operation}, nil
+}
+
 type sshkeypair struct {
 	privatekey []byte
 	publickey  []byte
@@ -730,164 +1090,147 @@ func (c *config) sshKeyPair(clusterID string) (*sshkeypair, error) {
 }
 
 func (c *config) reconcileCluster(args []string) error {
-	ci, err := c.clusterID("reconcile", args)
+	body, err := clusterReconcileRequestFromFlags()
 	if err != nil {
 		return err
 	}
 
-	request := cluster.NewReconcileClusterParams()
-	request.SetID(ci)
-
-	if helper.ViperBool("retry") != nil && helper.ViperBool("maintain") != nil {
-		return fmt.Errorf("--retry and --maintain are mutually exclusive")
+	if selector := viper.GetStringSlice("selector"); len(selector) > 0 {
+		if len(args) > 0 {
+			return fmt.Errorf("either a cluster id or --selector can be given")
+		}
+		return c.reconcileClusters(selector, body)
 	}
 
-	var operation *string
-	if viper.GetBool("retry") {
-		o := "retry"
-		operation = &o
+	ci, err := c.clusterID("reconcile", args)
+	if err != nil {
+		return err
 	}
-	if viper.GetBool("maintain") {
-		o := "maintain"
-		operation = &o
+
+	var since time.Time
+	if viper.GetBool("wait") {
+		current, err := c.findClusterWithoutMachines(ci)
+		if err != nil {
+			return err
+		}
+		since = lastOperationTime(current)
 	}
-	request.Body = &models.V1ClusterReconcileRequest{Operation: operation}
+
+	request := cluster.NewReconcileClusterParams()
+	request.SetID(ci)
+	request.Body = body
 
 	shoot, err := c.cloud.Cluster.ReconcileCluster(request, nil)
 	if err != nil {
 		return err
 	}
-	return output.New().Print(shoot.Payload)
+	result, err := c.waitForClusterIfRequested(shoot.Payload, clusterWaitForSucceeded, since)
+	if err != nil {
+		return err
+	}
+	return output.New().Print(result)
 }
 
 func (c *config) updateCluster(args []string) error {
+	if selector := viper.GetStringSlice("selector"); len(selector) > 0 {
+		if len(args) > 0 {
+			return fmt.Errorf("either a cluster id or --selector can be given")
+		}
+		return c.updateClusters(selector)
+	}
+
 	ci, err := c.clusterID("update", args)
 	if err != nil {
 		return err
 	}
+
+	findRequest := cluster.NewFindClusterParams()
+	findRequest.SetID(ci)
+	resp, err := c.cloud.Cluster.FindCluster(findRequest, nil)
+	if err != nil {
+		return err
+	}
+	current := resp.Payload
+
+	cur, err := clusterUpdateRequestFromFlags(current)
+	if err != nil {
+		return err
+	}
+
+	if viper.GetBool("dry-run") {
+		return printClusterUpdateDryRun(current, cur)
+	}
+
+	err = enforceClusterUpdatePolicies(current, cur)
+	if err != nil {
+		return err
+	}
+
+	err = confirmClusterUpdate(current, cur)
+	if err != nil {
+		return err
+	}
+
+	request := cluster.NewUpdateClusterParams()
+	request.SetBody(cur)
+	shoot, err := c.cloud.Cluster.UpdateCluster(request, nil)
+	if err != nil {
+		return err
+	}
+	result, err := c.waitForClusterIfRequested(shoot.Payload, clusterWaitForSucceeded, lastOperationTime(current))
+	if err != nil {
+		return err
+	}
+	return output.New().Print(result)
+}
+
+// clusterUpdateRequestFromFlags returns the update request for the given cluster with the changes given by flags.
+func clusterUpdateRequestFromFlags(current *models.V1ClusterResponse) (*models.V1ClusterUpdateRequest, error) {
 	workergroupname := viper.GetString("workergroup")
-	minsize := viper.GetInt32("minsize")
-	maxsize := viper.GetInt32("maxsize")
 	version := viper.GetString("version")
 	seed := viper.GetString("seed")
 	firewallType := viper.GetString("firewalltype")
 	firewallImage := viper.GetString("firewallimage")
 	firewallController := viper.GetString("firewallcontroller")
 	firewallNetworks := viper.GetStringSlice("external-networks")
-	machineType := viper.GetString("machinetype")
-	machineImageAndVersion := viper.GetString("machineimage")
 	purpose := viper.GetString("purpose")
 	addLabels := viper.GetStringSlice("addlabels")
 	removeLabels := viper.GetStringSlice("removelabels")
 	egress := viper.GetStringSlice("egress")
-	maxsurge := viper.GetString("maxsurge")
-	maxunavailable := viper.GetString("maxunavailable")
-
-	reversedVPN := strconv.FormatBool(viper.GetBool("reversed-vpn"))
-
-	findRequest := cluster.NewFindClusterParams()
-	findRequest.SetID(ci)
-	resp, err := c.cloud.Cluster.FindCluster(findRequest, nil)
-	if err != nil {
-		return err
-	}
-	current := resp.Payload
-
-	healthtimeout := viper.GetDuration("healthtimeout")
-	draintimeout := viper.GetDuration("draintimeout")
 
-	request := cluster.NewUpdateClusterParams()
 	cur := &models.V1ClusterUpdateRequest{
-		ID: &ci,
+		ID: current.ID,
 		Maintenance: &models.V1Maintenance{
 			AutoUpdate: &models.V1MaintenanceAutoUpdate{
 				KubernetesVersion: current.Maintenance.AutoUpdate.KubernetesVersion,
 				MachineImage:      current.Maintenance.AutoUpdate.MachineImage,
 			},
 		},
-		ClusterFeatures: &models.V1ClusterFeatures{
-			ReversedVPN: &reversedVPN,
-		},
 	}
 
-	if minsize != 0 || maxsize != 0 || machineImageAndVersion != "" || machineType != "" || viper.IsSet("healthtimeout") || viper.IsSet("draintimeout") || maxsurge != "" || maxunavailable != "" {
-		workers := current.Workers
-
-		var worker *models.V1Worker
-		if workergroupname != "" {
-			for _, w := range workers {
-				if w.Name != nil && *w.Name == workergroupname {
-					worker = w
-					break
-				}
-			}
-			if worker == nil {
-				return fmt.Errorf("no worker group found by name: %s", workergroupname)
-			}
-		} else if len(workers) == 1 {
-			worker = workers[0]
-		} else {
-			return fmt.Errorf("there are multiple worker groups, please specify the worker group you want to update with --workergroup")
-		}
-
-		if minsize != 0 {
-			worker.Minimum = &minsize
-		}
-		if maxsize != 0 {
-			worker.Maximum = &maxsize
-		}
+	if workerFlagsGiven() {
+		// the workers are copied to keep the current state of the cluster intact for comparison
+		workers := copyWorkers(current.Workers)
 
-		if machineImageAndVersion != "" {
-			machineImage := models.V1MachineImage{}
-			machineImageParts := strings.Split(machineImageAndVersion, "-")
-			if len(machineImageParts) == 2 {
-				machineImage = models.V1MachineImage{
-					Name:    &machineImageParts[0],
-					Version: &machineImageParts[1],
-				}
-			} else {
-				log.Fatalf("given machineimage:%s is invalid must be in the form <name>-<version>", machineImageAndVersion)
-			}
-			worker.MachineImage = &machineImage
+		worker, err := findWorkerGroup(workers, workergroupname, "workergroup")
+		if err != nil {
+			return nil, err
 		}
 
-		if machineType != "" {
-			worker.MachineType = &machineType
+		err = updateWorkerFromFlags(worker, current)
+		if err != nil {
+			return nil, err
 		}
 
-		mcmMigrated := false
-		for _, feature := range current.ControlPlaneFeatureGates {
-			if feature == "machineControllerManagerOOT" {
-				mcmMigrated = true
-				break
-			}
-		}
+		cur.Workers = append(cur.Workers, workers...)
+	}
 
-		if viper.IsSet("healthtimeout") {
-			if !mcmMigrated {
-				log.Fatal("custom healthtimeout requires feature: machineControllerManagerOOT")
-			}
-			worker.HealthTimeout = int64(healthtimeout)
+	if viper.IsSet("reversed-vpn") {
+		reversedVPN := strconv.FormatBool(viper.GetBool("reversed-vpn"))
+		cur.ClusterFeatures = &models.V1ClusterFeatures{
+			ReversedVPN: &reversedVPN,
 		}
-
-		if viper.IsSet("draintimeout") {
-			if !mcmMigrated {
-				log.Fatal("custom draintimeout requires feature: machineControllerManagerOOT")
-			}
-			worker.DrainTimeout = int64(draintimeout)
-		}
-
-		if maxsurge != "" {
-			worker.MaxSurge = &maxsurge
-		}
-
-		if maxunavailable != "" {
-			worker.MaxUnavailable = &maxunavailable
-		}
-
-		cur.Workers = append(cur.Workers, workers...)
 	}
-
 	if viper.IsSet("autoupdate-kubernetes") {
 		auto := viper.GetBool("autoupdate-kubernetes")
 		cur.Maintenance.AutoUpdate.KubernetesVersion = &auto
@@ -896,27 +1239,24 @@ func (c *config) updateCluster(args []string) error {
 		auto := viper.GetBool("autoupdate-machineimages")
 		cur.Maintenance.AutoUpdate.MachineImage = &auto
 	}
+	if viper.GetString("maintenance-begin") != "" || viper.GetString("maintenance-end") != "" {
+		timeWindow, err := maintenanceTimeWindowFromFlags(viper.GetString("maintenance-begin"), viper.GetString("maintenance-end"), current.Maintenance.TimeWindow, time.Now())
+		if err != nil {
+			return nil, err
+		}
+		cur.Maintenance.TimeWindow = timeWindow
+	}
 
-	updateCausesDowntime := false
 	if firewallImage != "" {
-		if current.FirewallImage != nil && *current.FirewallImage != firewallImage {
-			updateCausesDowntime = true
-		}
 		cur.FirewallImage = &firewallImage
 	}
 	if firewallType != "" {
-		if current.FirewallSize != nil && *current.FirewallSize != firewallType {
-			updateCausesDowntime = true
-		}
 		cur.FirewallSize = &firewallType
 	}
 	if firewallController != "" {
 		cur.FirewallControllerVersion = &firewallController
 	}
 	if len(firewallNetworks) > 0 {
-		if !sets.NewString(firewallNetworks...).Equal(sets.NewString(current.AdditionalNetworks...)) {
-			updateCausesDowntime = true
-		}
 		cur.AdditionalNetworks = firewallNetworks
 	}
 
@@ -929,7 +1269,10 @@ func (c *config) updateCluster(args []string) error {
 	}
 
 	if len(addLabels) > 0 || len(removeLabels) > 0 {
-		labelMap := current.Labels
+		labelMap := map[string]string{}
+		for k, v := range current.Labels {
+			labelMap[k] = v
+		}
 
 		for _, l := range removeLabels {
 			parts := strings.SplitN(l, "=", 2)
@@ -952,7 +1295,7 @@ func (c *config) updateCluster(args []string) error {
 	}
 	if viper.IsSet("allowprivileged") {
 		if !viper.GetBool("yes-i-really-mean-it") {
-			return fmt.Errorf("allowprivileged is set but you forgot to add --yes-i-really-mean-it")
+			return nil, fmt.Errorf("allowprivileged is set but you forgot to add --yes-i-really-mean-it")
 		}
 		allowPrivileged := viper.GetBool("allowprivileged")
 		k8s.AllowPrivilegedContainers = &allowPrivileged
@@ -963,27 +1306,486 @@ func (c *config) updateCluster(args []string) error {
 		audit := viper.GetString("audit")
 		auditConfig, ok := auditConfigOptions[audit]
 		if !ok {
-			return fmt.Errorf("audit value %s is not supported; choose one of %v", audit, auditConfigOptions.Names(false))
+			return nil, fmt.Errorf("audit value %s is not supported; choose one of %v", audit, auditConfigOptions.Names(false))
 		}
 		cur.Audit = auditConfig.Config
 	}
 
 	cur.EgressRules = makeEgressRules(egress)
 
-	if updateCausesDowntime && !viper.GetBool("yes-i-really-mean-it") {
-		fmt.Println("This cluster update will cause downtime.")
-		err = helper.Prompt("Are you sure? (y/n)", "y")
+	return cur, nil
+}
+
+func (c *config) clusterApply() error {
+	var ccrs []models.V1ClusterCreateRequest
+	var ccr models.V1ClusterCreateRequest
+	err := helper.ReadFrom(viper.GetString("file"), &ccr, func(data interface{}) {
+		doc := data.(*models.V1ClusterCreateRequest)
+		ccrs = append(ccrs, *doc)
+		// the request needs to be renewed as otherwise the pointers in the request struct will
+		// always point to same last value in the multi-document loop
+		ccr = models.V1ClusterCreateRequest{}
+	})
+	if err != nil {
+		return err
+	}
+
+	var response []*models.V1ClusterResponse
+	for i := range ccrs {
+		desired := &ccrs[i]
+		if desired.ProjectID == nil || *desired.ProjectID == "" || desired.Name == nil || *desired.Name == "" {
+			return fmt.Errorf("cluster apply requires project and name to be set in every document")
+		}
+
+		fcp := cluster.NewFindClustersParams().WithReturnMachines(pointer.BoolPtr(false))
+		fcp.SetBody(&models.V1ClusterFindRequest{
+			ProjectID: desired.ProjectID,
+			Name:      desired.Name,
+		})
+		found, err := c.cloud.Cluster.FindClusters(fcp, nil)
 		if err != nil {
 			return err
 		}
+
+		switch len(found.Payload) {
+		case 0:
+			if desired.Kubernetes != nil && pointer.BoolDeref(desired.Kubernetes.AllowPrivilegedContainers, false) && !viper.GetBool("yes-i-really-mean-it") {
+				return fmt.Errorf("allowprivileged of cluster %s is set but you forgot to add --yes-i-really-mean-it", *desired.Name)
+			}
+
+			err = enforceClusterPolicies(policyClusterFromCreateRequest(desired), nil)
+			if err != nil {
+				return err
+			}
+
+			request := cluster.NewCreateClusterParams()
+			request.SetBody(desired)
+			shoot, err := c.cloud.Cluster.CreateCluster(request, nil)
+			if err != nil {
+				return err
+			}
+			response = append(response, shoot.Payload)
+		case 1:
+			current := found.Payload[0]
+			cur := clusterUpdateRequestFromCreateRequest(current, desired)
+
+			if allowPrivilegedChanged(current, cur) && !viper.GetBool("yes-i-really-mean-it") {
+				return fmt.Errorf("allowprivileged of cluster %s is changed but you forgot to add --yes-i-really-mean-it", *desired.Name)
+			}
+
+			err = enforceClusterUpdatePolicies(current, cur)
+			if err != nil {
+				return err
+			}
+
+			err = confirmClusterUpdate(current, cur)
+			if err != nil {
+				return err
+			}
+
+			request := cluster.NewUpdateClusterParams()
+			request.SetBody(cur)
+			shoot, err := c.cloud.Cluster.UpdateCluster(request, nil)
+			if err != nil {
+				return err
+			}
+			response = append(response, shoot.Payload)
+		default:
+			return fmt.Errorf("cluster %s in project %s is ambiguous, found %d clusters", *desired.Name, *desired.ProjectID, len(found.Payload))
+		}
 	}
+	return output.New().Print(response)
+}
 
-	request.SetBody(cur)
-	shoot, err := c.cloud.Cluster.UpdateCluster(request, nil)
+func (c *config) clusterEdit(args []string) error {
+	ci, err := c.clusterID("edit", args)
 	if err != nil {
 		return err
 	}
-	return output.New().Print(shoot.Payload)
+
+	var current *models.V1ClusterResponse
+	getFunc := func(id string) ([]byte, error) {
+		findRequest := cluster.NewFindClusterParams().WithID(id).WithReturnMachines(pointer.BoolPtr(false))
+		resp, err := c.cloud.Cluster.FindCluster(findRequest, nil)
+		if err != nil {
+			return nil, fmt.Errorf("cluster describe error:%w", err)
+		}
+		current = resp.Payload
+		content, err := yaml.Marshal(clusterCreateRequestFromResponse(current))
+		if err != nil {
+			return nil, err
+		}
+		return content, nil
+	}
+	updateFunc := func(filename string) error {
+		var ccrs []models.V1ClusterCreateRequest
+		var ccr models.V1ClusterCreateRequest
+		err := helper.ReadFrom(filename, &ccr, func(data interface{}) {
+			doc := data.(*models.V1ClusterCreateRequest)
+			ccrs = append(ccrs, *doc)
+			ccr = models.V1ClusterCreateRequest{}
+		})
+		if err != nil {
+			return err
+		}
+		if len(ccrs) != 1 {
+			return fmt.Errorf("cluster update error more or less than one cluster given:%d", len(ccrs))
+		}
+		desired := &ccrs[0]
+
+		if pointer.StringDeref(desired.Name, "") != pointer.StringDeref(current.Name, "") ||
+			pointer.StringDeref(desired.ProjectID, "") != pointer.StringDeref(current.ProjectID, "") ||
+			pointer.StringDeref(desired.PartitionID, "") != pointer.StringDeref(current.PartitionID, "") {
+			return fmt.Errorf("name, project and partition of a cluster can not be changed")
+		}
+
+		cur := clusterUpdateRequestFromCreateRequest(current, desired)
+		err = validateClusterUpdateRequest(cur)
+		if err != nil {
+			return err
+		}
+
+		if allowPrivilegedChanged(current, cur) && !viper.GetBool("yes-i-really-mean-it") {
+			return fmt.Errorf("allowprivileged is changed but you forgot to add --yes-i-really-mean-it")
+		}
+
+		err = enforceClusterUpdatePolicies(current, cur)
+		if err != nil {
+			return err
+		}
+
+		err = confirmClusterUpdate(current, cur)
+		if err != nil {
+			return err
+		}
+
+		request := cluster.NewUpdateClusterParams()
+		request.SetBody(cur)
+		shoot, err := c.cloud.Cluster.UpdateCluster(request, nil)
+		if err != nil {
+			return err
+		}
+		return output.New().Print(shoot.Payload)
+	}
+
+	return helper.Edit(ci, getFunc, updateFunc)
+}
+
+func (c *config) clusterExport(args []string) error {
+	project := viper.GetString("project")
+
+	var clusters []*models.V1ClusterResponse
+	switch {
+	case len(args) > 0 && project != "":
+		return fmt.Errorf("either a cluster id or --project can be given")
+	case project != "":
+		fcp := cluster.NewFindClustersParams().WithReturnMachines(pointer.BoolPtr(false))
+		fcp.SetBody(&models.V1ClusterFindRequest{ProjectID: &project})
+		found, err := c.cloud.Cluster.FindClusters(fcp, nil)
+		if err != nil {
+			return err
+		}
+		clusters = found.Payload
+		sort.SliceStable(clusters, func(i, j int) bool {
+			return pointer.StringDeref(clusters[i].Name, "") < pointer.StringDeref(clusters[j].Name, "")
+		})
+	default:
+		ci, err := c.clusterID("export", args)
+		if err != nil {
+			return err
+		}
+		findRequest := cluster.NewFindClusterParams().WithID(ci).WithReturnMachines(pointer.BoolPtr(false))
+		resp, err := c.cloud.Cluster.FindCluster(findRequest, nil)
+		if err != nil {
+			return err
+		}
+		clusters = append(clusters, resp.Payload)
+	}
+
+	out := os.Stdout
+	if filename := viper.GetString("file"); filename != "" {
+		f, err := os.OpenFile(filename, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+	return exportClusters(out, clusters)
+}
+
+// exportClusters writes the specifications of the given clusters as yaml documents to w.
+func exportClusters(w io.Writer, clusters []*models.V1ClusterResponse) error {
+	enc := yaml.NewEncoder(w)
+	for _, current := range clusters {
+		var doc yaml.Node
+		err := doc.Encode(clusterCreateRequestFromResponse(current))
+		if err != nil {
+			return err
+		}
+		omitUnsetFields(&doc)
+		err = enc.Encode(&doc)
+		if err != nil {
+			return err
+		}
+	}
+	return enc.Close()
+}
+
+// omitUnsetFields removes fields which are null or empty lists and maps from the given yaml node. yaml encodes nil
+// slices and maps as empty ones, which would turn unset fields into fields to be cleared on cluster apply.
+func omitUnsetFields(n *yaml.Node) {
+	if n.Kind != yaml.MappingNode {
+		for _, c := range n.Content {
+			omitUnsetFields(c)
+		}
+		return
+	}
+	var content []*yaml.Node
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		key, value := n.Content[i], n.Content[i+1]
+		if value.Tag == "!!null" || ((value.Kind == yaml.SequenceNode || value.Kind == yaml.MappingNode) && len(value.Content) == 0) {
+			continue
+		}
+		omitUnsetFields(value)
+		content = append(content, key, value)
+	}
+	n.Content = content
+}
+
+// clusterCreateRequestFromResponse returns the user defined specification of the given cluster,
+// server-generated fields and the status are left out.
+func clusterCreateRequestFromResponse(current *models.V1ClusterResponse) *models.V1ClusterCreateRequest {
+	ccr := &models.V1ClusterCreateRequest{
+		Name:                      current.Name,
+		Description:               current.Description,
+		ProjectID:                 current.ProjectID,
+		PartitionID:               current.PartitionID,
+		Purpose:                   current.Purpose,
+		Labels:                    current.Labels,
+		Workers:                   current.Workers,
+		FirewallSize:              current.FirewallSize,
+		FirewallImage:             current.FirewallImage,
+		FirewallControllerVersion: current.FirewallControllerVersion,
+		Maintenance:               current.Maintenance,
+		AdditionalNetworks:        current.AdditionalNetworks,
+		EgressRules:               current.EgressRules,
+		ClusterFeatures:           current.ClusterFeatures,
+		Audit:                     auditConfigOptions[clusterAuditName(current)].Config,
+	}
+	if current.Kubernetes != nil {
+		ccr.Kubernetes = &models.V1Kubernetes{
+			Version:                   current.Kubernetes.Version,
+			AllowPrivilegedContainers: current.Kubernetes.AllowPrivilegedContainers,
+		}
+	}
+	return ccr
+}
+
+// applyClusterCreateSource takes over the configuration of the source cluster into the create request for every
+// field which was not explicitly given by a flag. identity-bound fields like name, project and egress ips are not taken over.
+func applyClusterCreateSource(scr *models.V1ClusterCreateRequest, source *models.V1ClusterResponse, isSet func(flag string) bool) {
+	if !isSet("purpose") {
+		scr.Purpose = source.Purpose
+	}
+	if !isSet("labels") && len(source.Labels) > 0 {
+		scr.Labels = map[string]string{}
+		for k, v := range source.Labels {
+			scr.Labels[k] = v
+		}
+	}
+	if !isSet("firewalltype") {
+		scr.FirewallSize = source.FirewallSize
+	}
+	if !isSet("firewallimage") {
+		scr.FirewallImage = source.FirewallImage
+	}
+	if !isSet("firewallcontroller") {
+		scr.FirewallControllerVersion = source.FirewallControllerVersion
+	}
+	if !isSet("external-networks") {
+		scr.AdditionalNetworks = append([]string{}, source.AdditionalNetworks...)
+	}
+	if !isSet("audit") {
+		scr.Audit = auditConfigOptions[clusterAuditName(source)].Config
+	}
+
+	if len(source.Workers) == 0 {
+		return
+	}
+	// worker flags given explicitly override the respective setting of every worker group of the source
+	override := scr.Workers[0]
+	var workers []*models.V1Worker
+	for _, sw := range source.Workers {
+		w := *sw
+		if isSet("minsize") {
+			w.Minimum = override.Minimum
+		}
+		if isSet("maxsize") {
+			w.Maximum = override.Maximum
+		}
+		if isSet("maxsurge") {
+			w.MaxSurge = override.MaxSurge
+		}
+		if isSet("maxunavailable") {
+			w.MaxUnavailable = override.MaxUnavailable
+		}
+		if isSet("machinetype") {
+			w.MachineType = override.MachineType
+		}
+		if isSet("machineimage") {
+			w.MachineImage = override.MachineImage
+		}
+		if isSet("cri") {
+			w.CRI = override.CRI
+		}
+		if isSet("healthtimeout") {
+			w.HealthTimeout = override.HealthTimeout
+		}
+		if isSet("draintimeout") {
+			w.DrainTimeout = override.DrainTimeout
+		}
+		workers = append(workers, &w)
+	}
+	scr.Workers = workers
+}
+
+// clusterAuditName returns the name of the audit option which is active for the given cluster
+func clusterAuditName(current *models.V1ClusterResponse) string {
+	var clusterAudit, auditToSplunk bool
+	for _, featureGate := range current.ControlPlaneFeatureGates {
+		switch featureGate {
+		case "clusterAudit":
+			clusterAudit = true
+		case "auditToSplunk":
+			auditToSplunk = true
+		}
+	}
+	switch {
+	case auditToSplunk:
+		return "splunk"
+	case clusterAudit:
+		return "on"
+	default:
+		return "off"
+	}
+}
+
+// validateClusterUpdateRequest checks the parts of an update request which can be edited by the user
+func validateClusterUpdateRequest(cur *models.V1ClusterUpdateRequest) error {
+	for _, w := range cur.Workers {
+		err := w.Validate(strfmt.Default)
+		if err != nil {
+			return fmt.Errorf("invalid worker group: %w", err)
+		}
+		if *w.Minimum > *w.Maximum {
+			return fmt.Errorf("minimum of worker group %s must not be greater than its maximum", *w.Name)
+		}
+	}
+	for _, e := range cur.EgressRules {
+		if e == nil || e.NetworkID == nil || *e.NetworkID == "" {
+			return fmt.Errorf("egress rules require a network")
+		}
+		for _, ip := range e.IPs {
+			if net.ParseIP(ip) == nil {
+				return fmt.Errorf("egress rule contains an invalid IP %s for network %s", ip, *e.NetworkID)
+			}
+		}
+	}
+	if cur.Maintenance != nil && cur.Maintenance.TimeWindow != nil {
+		err := cur.Maintenance.TimeWindow.Validate(strfmt.Default)
+		if err != nil {
+			return fmt.Errorf("invalid maintenance time window: %w", err)
+		}
+	}
+	return nil
+}
+
+// clusterUpdateRequestFromCreateRequest computes the update request that turns the current cluster into the desired one,
+// fields that are not set in the desired cluster are not changed.
+func clusterUpdateRequestFromCreateRequest(current *models.V1ClusterResponse, desired *models.V1ClusterCreateRequest) *models.V1ClusterUpdateRequest {
+	cur := &models.V1ClusterUpdateRequest{
+		ID:                 current.ID,
+		Labels:             desired.Labels,
+		Maintenance:        desired.Maintenance,
+		EgressRules:        desired.EgressRules,
+		AdditionalNetworks: desired.AdditionalNetworks,
+		Purpose:            desired.Purpose,
+		Audit:              desired.Audit,
+		ClusterFeatures:    desired.ClusterFeatures,
+	}
+
+	if desired.SeedName != "" {
+		cur.SeedName = &desired.SeedName
+	}
+	if desired.FirewallImage != nil && *desired.FirewallImage != "" {
+		cur.FirewallImage = desired.FirewallImage
+	}
+	if desired.FirewallSize != nil && *desired.FirewallSize != "" {
+		cur.FirewallSize = desired.FirewallSize
+	}
+	if desired.FirewallControllerVersion != nil && *desired.FirewallControllerVersion != "" {
+		cur.FirewallControllerVersion = desired.FirewallControllerVersion
+	}
+
+	k8s := &models.V1Kubernetes{}
+	if desired.Kubernetes != nil {
+		if desired.Kubernetes.Version != nil && *desired.Kubernetes.Version != "" {
+			k8s.Version = desired.Kubernetes.Version
+		}
+		k8s.AllowPrivilegedContainers = desired.Kubernetes.AllowPrivilegedContainers
+	}
+	cur.Kubernetes = k8s
+
+	if len(desired.Workers) > 0 {
+		for _, w := range desired.Workers {
+			worker := *w
+			// a cluster created from flags has a single worker group with a server-generated name,
+			// allow the desired spec to omit it in this case
+			if (worker.Name == nil || *worker.Name == "") && len(desired.Workers) == 1 && len(current.Workers) == 1 {
+				worker.Name = current.Workers[0].Name
+			}
+			cur.Workers = append(cur.Workers, &worker)
+		}
+	}
+
+	return cur
+}
+
+// allowPrivilegedChanged returns true if the update request modifies the allowprivileged setting of the cluster
+func allowPrivilegedChanged(current *models.V1ClusterResponse, cur *models.V1ClusterUpdateRequest) bool {
+	if cur.Kubernetes == nil || cur.Kubernetes.AllowPrivilegedContainers == nil {
+		return false
+	}
+	was := false
+	if current.Kubernetes != nil && current.Kubernetes.AllowPrivilegedContainers != nil {
+		was = *current.Kubernetes.AllowPrivilegedContainers
+	}
+	return was != *cur.Kubernetes.AllowPrivilegedContainers
+}
+
+// clusterUpdateCausesDowntime returns true if the given update request changes the firewall image, size or networks,
+// which requires the firewall to be recreated.
+func clusterUpdateCausesDowntime(current *models.V1ClusterResponse, cur *models.V1ClusterUpdateRequest) bool {
+	if cur.FirewallImage != nil && current.FirewallImage != nil && *current.FirewallImage != *cur.FirewallImage {
+		return true
+	}
+	if cur.FirewallSize != nil && current.FirewallSize != nil && *current.FirewallSize != *cur.FirewallSize {
+		return true
+	}
+	if len(cur.AdditionalNetworks) > 0 && !sets.NewString(cur.AdditionalNetworks...).Equal(sets.NewString(current.AdditionalNetworks...)) {
+		return true
+	}
+	return false
+}
+
+// confirmClusterUpdate prompts the user if the update causes downtime, can be skipped with --yes-i-really-mean-it
+func confirmClusterUpdate(current *models.V1ClusterResponse, cur *models.V1ClusterUpdateRequest) error {
+	if !clusterUpdateCausesDowntime(current, cur) || viper.GetBool("yes-i-really-mean-it") {
+		return nil
+	}
+	fmt.Printf("This update of cluster %s will cause downtime.\n", pointer.StringDeref(current.Name, *current.ID))
+	return helper.Prompt("Are you sure? (y/n)", "y")
 }
 
 func (c *config) clusterDelete(args []string) error {
@@ -1038,10 +1840,28 @@ func (c *config) clusterDescribe(args []string) error {
 	if err != nil {
 		return err
 	}
-	return output.New().Print(shoot.Payload)
+	err = output.New().Print(shoot.Payload)
+	if err != nil {
+		return err
+	}
+
+	format := viper.GetString("output-format")
+	if (format == "table" || format == "wide") && shoot.Payload.Maintenance != nil {
+		begin, end, err := nextMaintenanceWindow(shoot.Payload.Maintenance.TimeWindow, time.Now())
+		if err == nil {
+			fmt.Printf("\nNext maintenance window: %s - %s\n", begin.Local().Format("Mon 2006-01-02 15:04"), end.Local().Format("15:04 MST"))
+		}
+	}
+	return nil
 }
 
 func (c *config) clusterIssues(args []string) error {
+	failOn := viper.GetString("fail-on")
+	if failOn != "" && output.IssueSeverityLevel(failOn) < 0 {
+		return fmt.Errorf("fail-on value %s is not supported; choose one of %s", failOn, strings.Join(output.IssueSeverities, "|"))
+	}
+
+	var shoots []*models.V1ClusterResponse
 	if len(args) == 0 {
 		id := viper.GetString("id")
 		name := viper.GetString("name")
@@ -1077,28 +1897,76 @@ func (c *config) clusterIssues(args []string) error {
 			if err != nil {
 				return err
 			}
-			return output.New().Print(output.ShootIssuesResponses(response.Payload))
+			shoots = response.Payload
+		} else {
+			request := cluster.NewListClustersParams().WithReturnMachines(&boolTrue)
+			response, err := c.cloud.Cluster.ListClusters(request, nil)
+			if err != nil {
+				return err
+			}
+			shoots = response.Payload
 		}
-
-		request := cluster.NewListClustersParams().WithReturnMachines(&boolTrue)
-		shoots, err := c.cloud.Cluster.ListClusters(request, nil)
+	} else {
+		ci, err := c.clusterID("issues", args)
 		if err != nil {
 			return err
 		}
-		return output.New().Print(output.ShootIssuesResponses(shoots.Payload))
+		findRequest := cluster.NewFindClusterParams()
+		findRequest.SetID(ci)
+		shoot, err := c.cloud.Cluster.FindCluster(findRequest, nil)
+		if err != nil {
+			return err
+		}
+		shoots = []*models.V1ClusterResponse{shoot.Payload}
 	}
 
-	ci, err := c.clusterID("issues", args)
-	if err != nil {
-		return err
+	issues := []output.ShootIssue{}
+	for _, shoot := range shoots {
+		issues = append(issues, output.ShootIssues(shoot)...)
+	}
+
+	var err error
+	switch {
+	case output.New().Type() != "table" && viper.GetBool("structured"):
+		err = output.New().Print(issues)
+	case len(args) > 0:
+		err = output.New().Print(output.ShootIssuesResponse(shoots[0]))
+	default:
+		err = output.New().Print(output.ShootIssuesResponses(shoots))
 	}
-	findRequest := cluster.NewFindClusterParams()
-	findRequest.SetID(ci)
-	shoot, err := c.cloud.Cluster.FindCluster(findRequest, nil)
 	if err != nil {
 		return err
 	}
-	return output.New().Print(output.ShootIssuesResponse(shoot.Payload))
+
+	return failOnIssues(issues, failOn)
+}
+
+// failOnIssues returns an error if there are issues with at least the given severity, the exit code is 2 if the most
+// urgent issue is a warning and 3 if it is critical, so they can be distinguished from other errors which exit with 1.
+func failOnIssues(issues []output.ShootIssue, failOn string) error {
+	if failOn == "" {
+		return nil
+	}
+	threshold := output.IssueSeverityLevel(failOn)
+	highest := -1
+	count := 0
+	for _, issue := range issues {
+		level := output.IssueSeverityLevel(issue.Severity)
+		if level < threshold {
+			continue
+		}
+		count++
+		if level > highest {
+			highest = level
+		}
+	}
+	if count == 0 {
+		return nil
+	}
+	return &exitCodeError{
+		code: highest + 2,
+		err:  fmt.Errorf("found %d issue(s) with severity %s or higher", count, failOn),
+	}
 }
 
 func (c *config) clusterMachines(args []string) error {
@@ -1131,6 +1999,9 @@ func (c *config) clusterLogs(args []string) error {
 	if err != nil {
 		return err
 	}
+	if viper.GetBool("follow") {
+		return c.clusterLogsFollow(ci)
+	}
 	findRequest := cluster.NewFindClusterParams()
 	findRequest.SetID(ci)
 	shoot, err := c.cloud.Cluster.FindCluster(findRequest, nil)
@@ -1175,11 +2046,156 @@ func (c *config) clusterLogs(args []string) error {
 	return output.New().Print(lastOperation)
 }
 
+type clusterLogEvent struct {
+	Time    string `json:"time"`
+	Kind    string `json:"kind"`
+	Type    string `json:"type,omitempty"`
+	State   string `json:"state,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+func (e clusterLogEvent) String() string {
+	line := fmt.Sprintf("%s %-9s", e.Time, e.Kind)
+	if e.Type != "" {
+		line += " " + e.Type
+	}
+	if e.State != "" {
+		line += " " + e.State
+	}
+	if e.Message != "" {
+		line += ": " + e.Message
+	}
+	return line
+}
+
+func (c *config) clusterLogsFollow(id string) error {
+	interval := viper.GetDuration("interval")
+	if interval <= 0 {
+		interval = clusterWaitPollInterval
+	}
+	asJSON := viper.GetString("output-format") == "json"
+
+	var last *models.V1beta1ShootStatus
+	for {
+		shoot, err := c.findClusterWithoutMachines(id)
+		if err != nil {
+			if !transientClusterError(err) {
+				return err
+			}
+			fmt.Fprintf(os.Stderr, "%s unable to fetch cluster, retrying in %s: %v\n", color.YellowString("⚠"), interval, err)
+			time.Sleep(interval)
+			continue
+		}
+
+		for _, e := range clusterLogEvents(last, shoot.Status, time.Now()) {
+			if asJSON {
+				line, err := json.Marshal(e)
+				if err != nil {
+					return err
+				}
+				fmt.Println(string(line))
+				continue
+			}
+			fmt.Println(e.String())
+		}
+
+		if shoot.Status != nil {
+			last = shoot.Status
+		}
+		time.Sleep(interval)
+	}
+}
+
+// transientClusterError returns true if fetching a cluster failed for a reason which may go away on retry, e.g. network
+// errors or server errors. client errors like a cluster which does not exist anymore are not transient.
+func transientClusterError(err error) bool {
+	var r *cluster.FindClusterDefault
+	if errors.As(err, &r) {
+		return r.Code() >= 500
+	}
+	return true
+}
+
+// clusterLogEvents returns the condition transitions, errors and progress changes between two states of a cluster
+func clusterLogEvents(previous, current *models.V1beta1ShootStatus, now time.Time) []clusterLogEvent {
+	if current == nil {
+		return nil
+	}
+	if previous == nil {
+		previous = &models.V1beta1ShootStatus{}
+	}
+	timestamp := func(t *string) string {
+		if t != nil && *t != "" {
+			return *t
+		}
+		return now.UTC().Format(time.RFC3339)
+	}
+
+	var events []clusterLogEvent
+
+	previousConditions := map[string]*models.V1beta1Condition{}
+	for _, condition := range previous.Conditions {
+		previousConditions[pointer.StringDeref(condition.Type, "")] = condition
+	}
+	for _, condition := range current.Conditions {
+		p, ok := previousConditions[pointer.StringDeref(condition.Type, "")]
+		if ok && pointer.StringDeref(p.Status, "") == pointer.StringDeref(condition.Status, "") &&
+			pointer.StringDeref(p.Reason, "") == pointer.StringDeref(condition.Reason, "") {
+			continue
+		}
+		events = append(events, clusterLogEvent{
+			Time:    timestamp(condition.LastTransitionTime),
+			Kind:    "condition",
+			Type:    pointer.StringDeref(condition.Type, ""),
+			State:   pointer.StringDeref(condition.Status, ""),
+			Message: pointer.StringDeref(condition.Message, ""),
+		})
+	}
+
+	previousErrors := map[string]bool{}
+	for _, e := range previous.LastErrors {
+		previousErrors[e.TaskID+e.LastUpdateTime+pointer.StringDeref(e.Description, "")] = true
+	}
+	for _, e := range current.LastErrors {
+		if previousErrors[e.TaskID+e.LastUpdateTime+pointer.StringDeref(e.Description, "")] {
+			continue
+		}
+		events = append(events, clusterLogEvent{
+			Time:    timestamp(&e.LastUpdateTime),
+			Kind:    "error",
+			Type:    e.TaskID,
+			Message: pointer.StringDeref(e.Description, ""),
+		})
+	}
+
+	operation := func(op *models.V1beta1LastOperation) string {
+		if op == nil {
+			return ""
+		}
+		return fmt.Sprintf("%s %s %d%% %s", pointer.StringDeref(op.Type, ""), pointer.StringDeref(op.State, ""), pointer.Int32Deref(op.Progress, 0), pointer.StringDeref(op.Description, ""))
+	}
+	if op := current.LastOperation; op != nil && operation(op) != operation(previous.LastOperation) {
+		events = append(events, clusterLogEvent{
+			Time:    timestamp(op.LastUpdateTime),
+			Kind:    "operation",
+			Type:    pointer.StringDeref(op.Type, ""),
+			State:   fmt.Sprintf("%s %d%%", pointer.StringDeref(op.State, ""), pointer.Int32Deref(op.Progress, 0)),
+			Message: pointer.StringDeref(op.Description, ""),
+		})
+	}
+
+	return events
+}
+
 func (c *config) clusterInputs() error {
+	partitions := viper.GetStringSlice("partition")
+	if len(partitions) > 1 {
+		return c.clusterInputsByPartition(partitions)
+	}
+
 	request := cluster.NewListConstraintsParams()
-	partition := viper.GetString("partition")
-	if partition != "" {
-		request.WithPartition(&partition)
+	if len(partitions) == 1 {
+		request.WithPartition(&partitions[0])
 	}
 	sc, err := c.cloud.Cluster.ListConstraints(request, nil)
 	if err != nil {
@@ -1189,6 +2205,25 @@ func (c *config) clusterInputs() error {
 	return output.New().Print(sc)
 }
 
+// clusterInputsByPartition compares the constraints of the given partitions
+func (c *config) clusterInputsByPartition(partitions []string) error {
+	result := &output.PartitionConstraints{
+		Partitions:  partitions,
+		Constraints: map[string]*models.V1ShootConstraints{},
+	}
+	for _, p := range partitions {
+		p := p
+		request := cluster.NewListConstraintsParams().WithPartition(&p)
+		sc, err := c.cloud.Cluster.ListConstraints(request, nil)
+		if err != nil {
+			return fmt.Errorf("unable to list constraints of partition %s: %w", p, err)
+		}
+		result.Constraints[p] = sc.Payload
+	}
+
+	return output.New().Print(result)
+}
+
 func (c *config) clusterSplunkConfigManifest() error {
 	secret := corev1.Secret{
 		TypeMeta:   v1.TypeMeta{Kind: "Secret", APIVersion: "v1"},
@@ -1246,6 +2281,22 @@ func (c *config) clusterMachineReset(args []string) error {
 	}
 	mid := viper.GetString("machineid")
 
+	if viper.GetBool("all") {
+		if mid != "" {
+			return fmt.Errorf("--machineid and --all are mutually exclusive")
+		}
+		return c.clusterMachineRollAll(cid, "reset", func(mid string) error {
+			request := cluster.NewResetMachineParams()
+			request.SetID(cid)
+			request.Body = &models.V1ClusterMachineResetRequest{Machineid: &mid}
+			_, err := c.cloud.Cluster.ResetMachine(request, nil)
+			return err
+		})
+	}
+	if mid == "" {
+		return fmt.Errorf("either --machineid or --all is required")
+	}
+
 	request := cluster.NewResetMachineParams()
 	request.SetID(cid)
 	request.Body = &models.V1ClusterMachineResetRequest{Machineid: &mid}
@@ -1268,6 +2319,22 @@ func (c *config) clusterMachineCycle(args []string) error {
 	}
 	mid := viper.GetString("machineid")
 
+	if viper.GetBool("all") {
+		if mid != "" {
+			return fmt.Errorf("--machineid and --all are mutually exclusive")
+		}
+		return c.clusterMachineRollAll(cid, "cycle", func(mid string) error {
+			request := cluster.NewCycleMachineParams()
+			request.SetID(cid)
+			request.Body = &models.V1ClusterMachineCycleRequest{Machineid: &mid}
+			_, err := c.cloud.Cluster.CycleMachine(request, nil)
+			return err
+		})
+	}
+	if mid == "" {
+		return fmt.Errorf("either --machineid or --all is required")
+	}
+
 	request := cluster.NewCycleMachineParams()
 	request.SetID(cid)
 	request.Body = &models.V1ClusterMachineCycleRequest{Machineid: &mid}
@@ -1331,50 +2398,49 @@ func (c *config) clusterMachineSSH(args []string, console bool) error {
 	ms = append(ms, shoot.Payload.Firewalls...)
 	for _, m := range ms {
 		if *m.ID == mid {
-			home, err := os.UserHomeDir()
-			if err != nil {
-				return fmt.Errorf("unable determine home directory:%w", err)
-			}
-			privateKeyFile := path.Join(home, "."+c.name, "."+cid+".id_rsa")
-			err = os.WriteFile(privateKeyFile, keypair.privatekey, 0600)
-			if err != nil {
-				return fmt.Errorf("unable to write private key:%s error:%w", privateKeyFile, err)
-			}
-			defer os.Remove(privateKeyFile)
 			if console {
 				fmt.Printf("access console via ssh\n")
 				authContext, err := api.GetAuthContext(viper.GetString("kubeconfig"))
 				if err != nil {
 					return err
 				}
-				err = os.Setenv("LC_METAL_STACK_OIDC_TOKEN", authContext.IDToken)
+				client, err := sshConnect(net.JoinHostPort(c.consoleHost, bmcConsolePort), mid, keypair.privatekey)
 				if err != nil {
 					return err
 				}
-				bmcConsolePort := "5222"
-				err = ssh("-i", privateKeyFile, mid+"@"+c.consoleHost, "-p", bmcConsolePort)
-				return err
+				defer client.Close()
+				return sshShell(client, map[string]string{"LC_METAL_STACK_OIDC_TOKEN": authContext.IDToken})
 			}
-			networks := m.Allocation.Networks
 			feature := m.Allocation.Image.Features[0]
 			switch feature {
 			case "firewall":
-				for _, nw := range networks {
-					if *nw.Underlay || *nw.Private {
-						continue
-					}
-					for _, ip := range nw.Ips {
-						if portOpen(ip, "22", time.Second) {
-							err := ssh("-i", privateKeyFile, "metal"+"@"+ip)
-							return err
-						}
-					}
+				ip, err := firewallSSHIP(m)
+				if err != nil {
+					return err
+				}
+				client, err := sshConnect(net.JoinHostPort(ip, sshPort), firewallSSHUser, keypair.privatekey)
+				if err != nil {
+					return err
 				}
-				return fmt.Errorf("no ip with a open ssh port found")
+				defer client.Close()
+				return sshShell(client, nil)
 			case "machine":
-				// FIXME metal user is not allowed to execute
-				// ip vrf exec <tenantvrf> ssh <machineip>
-				return fmt.Errorf("machine access via ssh not implemented")
+				// workers are only reachable from the tenant vrf of a firewall, the connection is opened from within it
+				ip, err := machinePrivateIP(m)
+				if err != nil {
+					return err
+				}
+				jumpHost, vrf, err := firewallTenantVRFClient(shoot.Payload, keypair.privatekey)
+				if err != nil {
+					return err
+				}
+				defer jumpHost.Close()
+				client, err := sshConnectVRF(jumpHost, vrf, net.JoinHostPort(ip, sshPort), workerSSHUser, keypair.privatekey)
+				if err != nil {
+					return err
+				}
+				defer client.Close()
+				return sshShell(client, nil)
 			default:
 				return fmt.Errorf("unknown machine type:%s", feature)
 			}
@@ -1384,19 +2450,6 @@ func (c *config) clusterMachineSSH(args []string, console bool) error {
 	return fmt.Errorf("machine:%s not found in cluster:%s", mid, cid)
 }
 
-func ssh(args ...string) error {
-	path, err := exec.LookPath("ssh")
-	if err != nil {
-		return fmt.Errorf("unable to locate ssh in path")
-	}
-	fmt.Printf("%s %s\n", path, strings.Join(args, " "))
-	cmd := exec.Command(path, args...)
-	cmd.Stdin = os.Stdin
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stdout
-	return cmd.Run()
-}
-
 func portOpen(ip string, port string, timeout time.Duration) bool {
 	address := net.JoinHostPort(ip, port)
 	conn, err := net.DialTimeout("tcp", address, timeout)
@@ -1410,14 +2463,66 @@ func portOpen(ip string, port string, timeout time.Duration) bool {
 	return false
 }
 
+// clusterID returns the id of the cluster given as argument. the argument can be the id of the cluster, its name or a
+// unique prefix of its id, --project and --tenant narrow down the clusters which are taken into account.
 func (c *config) clusterID(verb string, args []string) (string, error) {
 	if len(args) == 0 {
 		return "", fmt.Errorf("cluster %s requires clusterID as argument", verb)
 	}
-	if len(args) == 1 {
+	if len(args) > 1 {
+		return "", fmt.Errorf("cluster %s requires exactly one clusterID as argument", verb)
+	}
+	if strfmt.IsUUID(args[0]) {
 		return args[0], nil
 	}
-	return "", fmt.Errorf("cluster %s requires exactly one clusterID as argument", verb)
+
+	cfr := &models.V1ClusterFindRequest{}
+	if project := viper.GetString("project"); project != "" {
+		cfr.ProjectID = &project
+	}
+	if tenant := viper.GetString("tenant"); tenant != "" {
+		cfr.Tenant = &tenant
+	}
+	fcp := cluster.NewFindClustersParams().WithReturnMachines(pointer.BoolPtr(false))
+	fcp.SetBody(cfr)
+	found, err := c.cloud.Cluster.FindClusters(fcp, nil)
+	if err != nil {
+		return "", err
+	}
+
+	return matchClusterID(args[0], found.Payload)
+}
+
+// matchClusterID returns the id of the only cluster which has the given name, if there is no cluster with this name
+// the id of the only cluster whose id starts with the given prefix is returned.
+func matchClusterID(nameOrPrefix string, clusters []*models.V1ClusterResponse) (string, error) {
+	var byName, byPrefix []*models.V1ClusterResponse
+	for _, shoot := range clusters {
+		if pointer.StringDeref(shoot.Name, "") == nameOrPrefix {
+			byName = append(byName, shoot)
+		}
+		if strings.HasPrefix(pointer.StringDeref(shoot.ID, ""), nameOrPrefix) {
+			byPrefix = append(byPrefix, shoot)
+		}
+	}
+
+	candidates := byName
+	if len(candidates) == 0 {
+		candidates = byPrefix
+	}
+
+	switch len(candidates) {
+	case 0:
+		return "", fmt.Errorf("no cluster found with name or id %q", nameOrPrefix)
+	case 1:
+		return *candidates[0].ID, nil
+	}
+
+	var lines []string
+	for _, shoot := range candidates {
+		lines = append(lines, fmt.Sprintf("  %s\t%s\tproject:%s\ttenant:%s", pointer.StringDeref(shoot.ID, ""), pointer.StringDeref(shoot.Name, ""), pointer.StringDeref(shoot.ProjectID, ""), pointer.StringDeref(shoot.Tenant, "")))
+	}
+	return "", fmt.Errorf("%q matches %d clusters, use the id or narrow down with --project or --tenant:\n%s", nameOrPrefix, len(candidates), strings.Join(lines, "\n"))
 }
 
 func makeEgressRules(egressFlagValue []string) []*models.V1EgressRule {
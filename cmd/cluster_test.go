@@ -0,0 +1,463 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fi-ts/cloud-go/api/client/cluster"
+	"github.com/fi-ts/cloud-go/api/models"
+	"github.com/fi-ts/cloudctl/cmd/helper"
+	"github.com/fi-ts/cloudctl/cmd/output"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/utils/pointer"
+)
+
+func Test_applyClusterCreateSource(t *testing.T) {
+	source := &models.V1ClusterResponse{
+		ID:                        pointer.StringPtr("c1"),
+		Name:                      pointer.StringPtr("prod"),
+		PartitionID:               pointer.StringPtr("fra-equ01"),
+		Purpose:                   pointer.StringPtr("production"),
+		Labels:                    map[string]string{"team": "a"},
+		FirewallSize:              pointer.StringPtr("c1-large-x86"),
+		FirewallImage:             pointer.StringPtr("firewall-ubuntu-2.0"),
+		FirewallControllerVersion: pointer.StringPtr("v1.1.0"),
+		AdditionalNetworks:        []string{"internet"},
+		ControlPlaneFeatureGates:  []string{"clusterAudit"},
+		EgressRules: []*models.V1EgressRule{
+			{NetworkID: pointer.StringPtr("internet"), IPs: []string{"1.2.3.4"}},
+		},
+		Workers: []*models.V1Worker{
+			{Name: pointer.StringPtr("group-0"), MachineType: pointer.StringPtr("c1-xlarge-x86"), Minimum: pointer.Int32Ptr(3), Maximum: pointer.Int32Ptr(6)},
+			{Name: pointer.StringPtr("group-1"), MachineType: pointer.StringPtr("s2-xlarge-x86"), Minimum: pointer.Int32Ptr(1), Maximum: pointer.Int32Ptr(2)},
+		},
+	}
+
+	newRequest := func() *models.V1ClusterCreateRequest {
+		return &models.V1ClusterCreateRequest{
+			Name:               pointer.StringPtr("staging"),
+			ProjectID:          pointer.StringPtr("p1"),
+			Purpose:            pointer.StringPtr("evaluation"),
+			FirewallSize:       pointer.StringPtr(""),
+			FirewallImage:      pointer.StringPtr(""),
+			AdditionalNetworks: []string{},
+			Audit:              auditConfigOptions["splunk"].Config,
+			Workers: []*models.V1Worker{
+				{MachineType: pointer.StringPtr("c1-large-x86"), Minimum: pointer.Int32Ptr(1), Maximum: pointer.Int32Ptr(1)},
+			},
+		}
+	}
+
+	t.Run("everything from source", func(t *testing.T) {
+		scr := newRequest()
+		applyClusterCreateSource(scr, source, func(string) bool { return false })
+
+		assert.Equal(t, "staging", *scr.Name)
+		assert.Equal(t, "production", *scr.Purpose)
+		assert.Equal(t, map[string]string{"team": "a"}, scr.Labels)
+		assert.Equal(t, "c1-large-x86", *scr.FirewallSize)
+		assert.Equal(t, "firewall-ubuntu-2.0", *scr.FirewallImage)
+		assert.Equal(t, "v1.1.0", *scr.FirewallControllerVersion)
+		assert.Equal(t, []string{"internet"}, scr.AdditionalNetworks)
+		assert.Equal(t, auditConfigOptions["on"].Config, scr.Audit)
+		assert.Nil(t, scr.EgressRules)
+		assert.Len(t, scr.Workers, 2)
+		assert.Equal(t, "c1-xlarge-x86", *scr.Workers[0].MachineType)
+		assert.Equal(t, int32(3), *scr.Workers[0].Minimum)
+	})
+
+	t.Run("explicit flags override the source", func(t *testing.T) {
+		set := map[string]bool{"purpose": true, "machinetype": true, "audit": true}
+		scr := newRequest()
+		applyClusterCreateSource(scr, source, func(flag string) bool { return set[flag] })
+
+		assert.Equal(t, "evaluation", *scr.Purpose)
+		assert.Equal(t, auditConfigOptions["splunk"].Config, scr.Audit)
+		assert.Len(t, scr.Workers, 2)
+		for _, w := range scr.Workers {
+			assert.Equal(t, "c1-large-x86", *w.MachineType)
+		}
+		assert.Equal(t, int32(3), *scr.Workers[0].Minimum)
+		assert.Equal(t, "s2-xlarge-x86", *source.Workers[1].MachineType, "source must not be modified")
+	})
+}
+
+func Test_exportClustersRoundTrip(t *testing.T) {
+	clusters := []*models.V1ClusterResponse{
+		{
+			ID:          pointer.StringPtr("c1"),
+			Name:        pointer.StringPtr("prod"),
+			ProjectID:   pointer.StringPtr("p1"),
+			PartitionID: pointer.StringPtr("fra-equ01"),
+			Kubernetes:  &models.V1Kubernetes{Version: pointer.StringPtr("1.21.5")},
+			Labels:      map[string]string{"team": "a"},
+			Workers: []*models.V1Worker{
+				{Name: pointer.StringPtr("group-0"), MachineType: pointer.StringPtr("c1-xlarge-x86"), Minimum: pointer.Int32Ptr(1), Maximum: pointer.Int32Ptr(2)},
+			},
+			Status:   &models.V1beta1ShootStatus{},
+			Machines: []*models.ModelsV1MachineResponse{{ID: pointer.StringPtr("m1")}},
+		},
+		{
+			ID:          pointer.StringPtr("c2"),
+			Name:        pointer.StringPtr("staging"),
+			ProjectID:   pointer.StringPtr("p1"),
+			PartitionID: pointer.StringPtr("fra-equ01"),
+			EgressRules: []*models.V1EgressRule{
+				{NetworkID: pointer.StringPtr("internet"), IPs: []string{"1.2.3.4"}},
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, exportClusters(&buf, clusters))
+	assert.NotContains(t, buf.String(), "m1", "machines must not be exported")
+	assert.NotContains(t, buf.String(), "[]", "unset lists must not be exported as empty lists")
+
+	file := filepath.Join(t.TempDir(), "clusters.yaml")
+	require.NoError(t, os.WriteFile(file, buf.Bytes(), 0600))
+
+	var ccrs []models.V1ClusterCreateRequest
+	var ccr models.V1ClusterCreateRequest
+	err := helper.ReadFrom(file, &ccr, func(data interface{}) {
+		ccrs = append(ccrs, *data.(*models.V1ClusterCreateRequest))
+		ccr = models.V1ClusterCreateRequest{}
+	})
+	require.NoError(t, err)
+	require.Len(t, ccrs, 2)
+	for i := range clusters {
+		assert.Equal(t, clusterCreateRequestFromResponse(clusters[i]), &ccrs[i])
+	}
+}
+
+func Test_matchClusterID(t *testing.T) {
+	clusters := []*models.V1ClusterResponse{
+		{ID: pointer.StringPtr("1d8636d7-dadb-11e9-9e70-8ebea97dd3a9"), Name: pointer.StringPtr("banking"), ProjectID: pointer.StringPtr("p1"), Tenant: pointer.StringPtr("t1")},
+		{ID: pointer.StringPtr("1d86a2c1-dadb-11e9-9e70-8ebea97dd3a9"), Name: pointer.StringPtr("shop"), ProjectID: pointer.StringPtr("p1"), Tenant: pointer.StringPtr("t1")},
+		{ID: pointer.StringPtr("8f1c3a9e-dadb-11e9-9e70-8ebea97dd3a9"), Name: pointer.StringPtr("shop"), ProjectID: pointer.StringPtr("p2"), Tenant: pointer.StringPtr("t1")},
+		{ID: pointer.StringPtr("5e2b7f10-dadb-11e9-9e70-8ebea97dd3a9"), Name: pointer.StringPtr("1d86"), ProjectID: pointer.StringPtr("p2"), Tenant: pointer.StringPtr("t1")},
+	}
+
+	tests := []struct {
+		name         string
+		nameOrPrefix string
+		want         string
+		wantErr      string
+	}{
+		{name: "by name", nameOrPrefix: "banking", want: "1d8636d7-dadb-11e9-9e70-8ebea97dd3a9"},
+		{name: "by unique prefix", nameOrPrefix: "8f1c", want: "8f1c3a9e-dadb-11e9-9e70-8ebea97dd3a9"},
+		{name: "not found", nameOrPrefix: "nothing", wantErr: `no cluster found with name or id "nothing"`},
+		{name: "name takes precedence over prefix", nameOrPrefix: "1d86", want: "5e2b7f10-dadb-11e9-9e70-8ebea97dd3a9"},
+		{name: "ambiguous prefix", nameOrPrefix: "1d8", wantErr: `"1d8" matches 2 clusters`},
+		{name: "ambiguous name", nameOrPrefix: "shop", wantErr: `"shop" matches 2 clusters`},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := matchClusterID(tt.nameOrPrefix, clusters)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_clusterUpdateRequestFromCreateRequest(t *testing.T) {
+	current := &models.V1ClusterResponse{
+		ID:   pointer.StringPtr("c1"),
+		Name: pointer.StringPtr("prod"),
+		Workers: []*models.V1Worker{
+			{Name: pointer.StringPtr("group-0"), Minimum: pointer.Int32Ptr(1)},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		desired *models.V1ClusterCreateRequest
+		want    *models.V1ClusterUpdateRequest
+	}{
+		{
+			name:    "omitted fields are left untouched",
+			desired: &models.V1ClusterCreateRequest{Name: pointer.StringPtr("prod")},
+			want:    &models.V1ClusterUpdateRequest{ID: pointer.StringPtr("c1"), Kubernetes: &models.V1Kubernetes{}},
+		},
+		{
+			name: "empty egress rules remove all egress rules",
+			desired: &models.V1ClusterCreateRequest{
+				Labels:      map[string]string{"team": "a"},
+				EgressRules: []*models.V1EgressRule{},
+			},
+			want: &models.V1ClusterUpdateRequest{
+				ID:          pointer.StringPtr("c1"),
+				Labels:      map[string]string{"team": "a"},
+				EgressRules: []*models.V1EgressRule{},
+				Kubernetes:  &models.V1Kubernetes{},
+			},
+		},
+		{
+			name: "empty firewall and kubernetes version are not updated",
+			desired: &models.V1ClusterCreateRequest{
+				FirewallImage:             pointer.StringPtr(""),
+				FirewallSize:              pointer.StringPtr("c1-large-x86"),
+				FirewallControllerVersion: pointer.StringPtr(""),
+				Kubernetes:                &models.V1Kubernetes{Version: pointer.StringPtr(""), AllowPrivilegedContainers: pointer.BoolPtr(true)},
+				SeedName:                  "seed-1",
+			},
+			want: &models.V1ClusterUpdateRequest{
+				ID:           pointer.StringPtr("c1"),
+				FirewallSize: pointer.StringPtr("c1-large-x86"),
+				Kubernetes:   &models.V1Kubernetes{AllowPrivilegedContainers: pointer.BoolPtr(true)},
+				SeedName:     pointer.StringPtr("seed-1"),
+			},
+		},
+		{
+			name: "single unnamed worker group takes over the name of the current one",
+			desired: &models.V1ClusterCreateRequest{
+				Workers: []*models.V1Worker{{Minimum: pointer.Int32Ptr(2)}},
+			},
+			want: &models.V1ClusterUpdateRequest{
+				ID:         pointer.StringPtr("c1"),
+				Kubernetes: &models.V1Kubernetes{},
+				Workers:    []*models.V1Worker{{Name: pointer.StringPtr("group-0"), Minimum: pointer.Int32Ptr(2)}},
+			},
+		},
+		{
+			name: "multiple worker groups are taken over as they are",
+			desired: &models.V1ClusterCreateRequest{
+				Workers: []*models.V1Worker{{Name: pointer.StringPtr("group-0")}, {Name: pointer.StringPtr("group-1")}},
+			},
+			want: &models.V1ClusterUpdateRequest{
+				ID:         pointer.StringPtr("c1"),
+				Kubernetes: &models.V1Kubernetes{},
+				Workers:    []*models.V1Worker{{Name: pointer.StringPtr("group-0")}, {Name: pointer.StringPtr("group-1")}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, clusterUpdateRequestFromCreateRequest(current, tt.desired))
+		})
+	}
+}
+
+func Test_validateClusterUpdateRequest(t *testing.T) {
+	worker := func(min, max int32) *models.V1Worker {
+		return &models.V1Worker{
+			Name:           pointer.StringPtr("group-0"),
+			CRI:            pointer.StringPtr("containerd"),
+			MachineType:    pointer.StringPtr("c1-xlarge-x86"),
+			MachineImage:   &models.V1MachineImage{Name: pointer.StringPtr("ubuntu"), Version: pointer.StringPtr("20.04")},
+			MaxSurge:       pointer.StringPtr("1"),
+			MaxUnavailable: pointer.StringPtr("0"),
+			Minimum:        pointer.Int32Ptr(min),
+			Maximum:        pointer.Int32Ptr(max),
+		}
+	}
+
+	tests := []struct {
+		name    string
+		cur     *models.V1ClusterUpdateRequest
+		wantErr string
+	}{
+		{
+			name: "empty update",
+			cur:  &models.V1ClusterUpdateRequest{},
+		},
+		{
+			name: "valid update",
+			cur: &models.V1ClusterUpdateRequest{
+				Workers:     []*models.V1Worker{worker(1, 3)},
+				EgressRules: []*models.V1EgressRule{{NetworkID: pointer.StringPtr("internet"), IPs: []string{"1.2.3.4", "2001:db8::1"}}},
+				Maintenance: &models.V1Maintenance{TimeWindow: &models.V1MaintenanceTimeWindow{Begin: pointer.StringPtr("010000+0000"), End: pointer.StringPtr("020000+0000")}},
+			},
+		},
+		{
+			name:    "worker group without machine type",
+			cur:     &models.V1ClusterUpdateRequest{Workers: []*models.V1Worker{func() *models.V1Worker { w := worker(1, 3); w.MachineType = nil; return w }()}},
+			wantErr: "invalid worker group",
+		},
+		{
+			name:    "minimum greater than maximum",
+			cur:     &models.V1ClusterUpdateRequest{Workers: []*models.V1Worker{worker(3, 1)}},
+			wantErr: "minimum of worker group group-0 must not be greater than its maximum",
+		},
+		{
+			name:    "egress rule without network",
+			cur:     &models.V1ClusterUpdateRequest{EgressRules: []*models.V1EgressRule{{IPs: []string{"1.2.3.4"}}}},
+			wantErr: "egress rules require a network",
+		},
+		{
+			name:    "egress rule with invalid ip",
+			cur:     &models.V1ClusterUpdateRequest{EgressRules: []*models.V1EgressRule{{NetworkID: pointer.StringPtr("internet"), IPs: []string{"1.2.3"}}}},
+			wantErr: "egress rule contains an invalid IP 1.2.3 for network internet",
+		},
+		{
+			name:    "maintenance time window without end",
+			cur:     &models.V1ClusterUpdateRequest{Maintenance: &models.V1Maintenance{TimeWindow: &models.V1MaintenanceTimeWindow{Begin: pointer.StringPtr("010000+0000")}}},
+			wantErr: "invalid maintenance time window",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateClusterUpdateRequest(tt.cur)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func Test_clusterUpdateCausesDowntime(t *testing.T) {
+	current := &models.V1ClusterResponse{
+		FirewallImage:      pointer.StringPtr("firewall-ubuntu-2.0"),
+		FirewallSize:       pointer.StringPtr("c1-large-x86"),
+		AdditionalNetworks: []string{"internet", "mpls"},
+	}
+
+	tests := []struct {
+		name string
+		cur  *models.V1ClusterUpdateRequest
+		want bool
+	}{
+		{name: "nothing changed", cur: &models.V1ClusterUpdateRequest{}, want: false},
+		{name: "same firewall image", cur: &models.V1ClusterUpdateRequest{FirewallImage: pointer.StringPtr("firewall-ubuntu-2.0")}, want: false},
+		{name: "firewall image changed", cur: &models.V1ClusterUpdateRequest{FirewallImage: pointer.StringPtr("firewall-ubuntu-3.0")}, want: true},
+		{name: "firewall size changed", cur: &models.V1ClusterUpdateRequest{FirewallSize: pointer.StringPtr("c1-xlarge-x86")}, want: true},
+		{name: "same networks in other order", cur: &models.V1ClusterUpdateRequest{AdditionalNetworks: []string{"mpls", "internet"}}, want: false},
+		{name: "network removed", cur: &models.V1ClusterUpdateRequest{AdditionalNetworks: []string{"internet"}}, want: true},
+		{name: "labels changed", cur: &models.V1ClusterUpdateRequest{Labels: map[string]string{"team": "b"}}, want: false},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, clusterUpdateCausesDowntime(current, tt.cur))
+		})
+	}
+}
+
+func Test_clusterLogEvents(t *testing.T) {
+	now := time.Date(2022, 1, 20, 10, 0, 0, 0, time.UTC)
+	condition := func(status, reason, transition string) *models.V1beta1Condition {
+		return &models.V1beta1Condition{
+			Type:               pointer.StringPtr("APIServerAvailable"),
+			Status:             pointer.StringPtr(status),
+			Reason:             pointer.StringPtr(reason),
+			Message:            pointer.StringPtr(reason),
+			LastTransitionTime: pointer.StringPtr(transition),
+			LastUpdateTime:     pointer.StringPtr("2022-01-20T09:59:00Z"),
+		}
+	}
+	operation := func(state string, progress int32) *models.V1beta1LastOperation {
+		return &models.V1beta1LastOperation{
+			Type:           pointer.StringPtr("Reconcile"),
+			State:          pointer.StringPtr(state),
+			Progress:       pointer.Int32Ptr(progress),
+			Description:    pointer.StringPtr("reconciling"),
+			LastUpdateTime: pointer.StringPtr("2022-01-20T09:58:00Z"),
+		}
+	}
+
+	tests := []struct {
+		name     string
+		previous *models.V1beta1ShootStatus
+		current  *models.V1beta1ShootStatus
+		want     []clusterLogEvent
+	}{
+		{
+			name:    "no status",
+			current: nil,
+			want:    nil,
+		},
+		{
+			name:    "first state reports everything",
+			current: &models.V1beta1ShootStatus{Conditions: []*models.V1beta1Condition{condition("True", "HealthzRequestSucceeded", "2022-01-20T09:00:00Z")}, LastOperation: operation("Processing", 10)},
+			want: []clusterLogEvent{
+				{Time: "2022-01-20T09:00:00Z", Kind: "condition", Type: "APIServerAvailable", State: "True", Message: "HealthzRequestSucceeded"},
+				{Time: "2022-01-20T09:58:00Z", Kind: "operation", Type: "Reconcile", State: "Processing 10%", Message: "reconciling"},
+			},
+		},
+		{
+			name:     "unchanged state reports nothing",
+			previous: &models.V1beta1ShootStatus{Conditions: []*models.V1beta1Condition{condition("True", "HealthzRequestSucceeded", "2022-01-20T09:00:00Z")}, LastOperation: operation("Processing", 10)},
+			current:  &models.V1beta1ShootStatus{Conditions: []*models.V1beta1Condition{condition("True", "HealthzRequestSucceeded", "2022-01-20T09:00:00Z")}, LastOperation: operation("Processing", 10)},
+			want:     nil,
+		},
+		{
+			name:     "condition transition",
+			previous: &models.V1beta1ShootStatus{Conditions: []*models.V1beta1Condition{condition("True", "HealthzRequestSucceeded", "2022-01-20T09:00:00Z")}},
+			current:  &models.V1beta1ShootStatus{Conditions: []*models.V1beta1Condition{condition("False", "HealthzRequestFailed", "2022-01-20T09:59:30Z")}},
+			want: []clusterLogEvent{
+				{Time: "2022-01-20T09:59:30Z", Kind: "condition", Type: "APIServerAvailable", State: "False", Message: "HealthzRequestFailed"},
+			},
+		},
+		{
+			name:     "operation progress and new error",
+			previous: &models.V1beta1ShootStatus{LastOperation: operation("Processing", 10)},
+			current: &models.V1beta1ShootStatus{
+				LastOperation: operation("Processing", 50),
+				LastErrors:    []*models.V1beta1LastError{{TaskID: "deploy-dns", Description: pointer.StringPtr("dns not ready")}},
+			},
+			want: []clusterLogEvent{
+				{Time: "2022-01-20T10:00:00Z", Kind: "error", Type: "deploy-dns", Message: "dns not ready"},
+				{Time: "2022-01-20T09:58:00Z", Kind: "operation", Type: "Reconcile", State: "Processing 50%", Message: "reconciling"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, clusterLogEvents(tt.previous, tt.current, now))
+		})
+	}
+}
+
+func Test_transientClusterError(t *testing.T) {
+	assert.True(t, transientClusterError(errors.New("connection refused")))
+	assert.True(t, transientClusterError(cluster.NewFindClusterDefault(http.StatusServiceUnavailable)))
+	assert.False(t, transientClusterError(cluster.NewFindClusterDefault(http.StatusNotFound)))
+}
+
+func Test_failOnIssues(t *testing.T) {
+	warning := output.ShootIssue{Severity: output.IssueSeverityWarning}
+	critical := output.ShootIssue{Severity: output.IssueSeverityCritical}
+
+	tests := []struct {
+		name     string
+		issues   []output.ShootIssue
+		failOn   string
+		wantCode int
+	}{
+		{name: "fail-on not given", issues: []output.ShootIssue{critical}, failOn: ""},
+		{name: "no issues", failOn: output.IssueSeverityWarning},
+		{name: "warning", issues: []output.ShootIssue{warning}, failOn: output.IssueSeverityWarning, wantCode: 2},
+		{name: "critical", issues: []output.ShootIssue{warning, critical}, failOn: output.IssueSeverityWarning, wantCode: 3},
+		{name: "warning below threshold", issues: []output.ShootIssue{warning}, failOn: output.IssueSeverityCritical},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			err := failOnIssues(tt.issues, tt.failOn)
+			if tt.wantCode == 0 {
+				require.NoError(t, err)
+				return
+			}
+			var exitErr *exitCodeError
+			require.ErrorAs(t, err, &exitErr)
+			assert.Equal(t, tt.wantCode, exitErr.code)
+		})
+	}
+}
@@ -0,0 +1,168 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/fi-ts/cloud-go/api/client/cluster"
+	"github.com/fi-ts/cloud-go/api/models"
+	"github.com/fi-ts/cloudctl/cmd/output"
+	"github.com/metal-stack/v"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"k8s.io/utils/pointer"
+)
+
+func newClusterAuditCmd(c *config) *cobra.Command {
+	auditCmd := &cobra.Command{
+		Use:   "audit",
+		Short: "check all clusters against best practice settings",
+		Long: `checks every visible cluster against best practice settings and reports the findings per cluster:
+
+  privileged-allowed            privileged containers are allowed
+  audit-off                     the kube-apiserver audit log is turned off
+  autoupdate-kubernetes-off     kubernetes patch versions are not updated automatically
+  autoupdate-machineimages-off  machine images are not updated automatically
+  konnectivity                  konnectivity is used instead of reversed-vpn
+  firewall-controller-outdated  the firewall controller is older than the newest available version
+  image-expiration              machine images are expired or expire soon
+  single-worker-production      a production cluster runs with a single worker
+
+use -o sarif to get the findings as SARIF log for compliance dashboards.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return c.clusterAudit()
+		},
+		PreRun: bindPFlags,
+	}
+
+	auditCmd.Flags().String("partition", "", "only check clusters in partition.")
+	must(auditCmd.RegisterFlagCompletionFunc("partition", c.comp.PartitionListCompletion))
+
+	return auditCmd
+}
+
+func (c *config) clusterAudit() error {
+	cfr := &models.V1ClusterFindRequest{}
+	if tenant := viper.GetString("tenant"); tenant != "" {
+		cfr.Tenant = &tenant
+	}
+	if project := viper.GetString("project"); project != "" {
+		cfr.ProjectID = &project
+	}
+	if partition := viper.GetString("partition"); partition != "" {
+		cfr.PartitionID = &partition
+	}
+	fcp := cluster.NewFindClustersParams().WithReturnMachines(pointer.BoolPtr(true))
+	fcp.SetBody(cfr)
+	response, err := c.cloud.Cluster.FindClusters(fcp, nil)
+	if err != nil {
+		return err
+	}
+
+	// the newest firewall controller is looked up once per partition
+	newestFirewallController := map[string]*semver.Version{}
+	findings := output.ClusterAuditFindings{}
+	for _, shoot := range response.Payload {
+		partition := pointer.StringDeref(shoot.PartitionID, "")
+		newest, ok := newestFirewallController[partition]
+		if !ok {
+			request := cluster.NewListConstraintsParams().WithPartition(&partition)
+			constraints, err := c.cloud.Cluster.ListConstraints(request, nil)
+			if err != nil {
+				return fmt.Errorf("unable to list constraints of partition %s: %w", partition, err)
+			}
+			newest = newestFirewallControllerVersion(constraints.Payload.FirewallControllerVersions)
+			newestFirewallController[partition] = newest
+		}
+		findings = append(findings, auditCluster(shoot, newest)...)
+	}
+
+	if viper.GetString("output-format") == "sarif" {
+		sarif, err := output.ClusterAuditSARIF(c.name, v.Version, findings)
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(sarif))
+		return nil
+	}
+	return output.New().Print(findings)
+}
+
+// newestFirewallControllerVersion returns the newest of the given firewall controller versions, nil if none can be parsed
+func newestFirewallControllerVersion(versions []*models.V1FirewallControllerVersion) *semver.Version {
+	var newest *semver.Version
+	for _, fc := range versions {
+		fv, err := semver.NewVersion(pointer.StringDeref(fc.Version, ""))
+		if err != nil {
+			continue
+		}
+		if newest == nil || fv.GreaterThan(newest) {
+			newest = fv
+		}
+	}
+	return newest
+}
+
+// auditCluster returns the best practice settings the given cluster does not comply with
+func auditCluster(shoot *models.V1ClusterResponse, newestFirewallController *semver.Version) output.ClusterAuditFindings {
+	var findings output.ClusterAuditFindings
+	add := func(rule, severity, message string) {
+		findings = append(findings, output.ClusterAuditFinding{
+			ClusterID:   pointer.StringDeref(shoot.ID, ""),
+			ClusterName: pointer.StringDeref(shoot.Name, ""),
+			ProjectID:   pointer.StringDeref(shoot.ProjectID, ""),
+			Tenant:      pointer.StringDeref(shoot.Tenant, ""),
+			Rule:        rule,
+			Severity:    severity,
+			Message:     message,
+		})
+	}
+
+	if shoot.Kubernetes != nil && pointer.BoolDeref(shoot.Kubernetes.AllowPrivilegedContainers, false) {
+		add(output.AuditRulePrivilegedAllowed, output.IssueSeverityCritical, "privileged containers are allowed")
+	}
+
+	if clusterAuditName(shoot) == "off" {
+		add(output.AuditRuleAuditOff, output.IssueSeverityWarning, "audit log is turned off")
+	}
+
+	if shoot.Maintenance != nil && shoot.Maintenance.AutoUpdate != nil {
+		if !pointer.BoolDeref(shoot.Maintenance.AutoUpdate.KubernetesVersion, false) {
+			add(output.AuditRuleAutoUpdateKubernetesOff, output.IssueSeverityWarning, "autoupdate of kubernetes is disabled")
+		}
+		if !pointer.BoolDeref(shoot.Maintenance.AutoUpdate.MachineImage, false) {
+			add(output.AuditRuleAutoUpdateMachineImagesOff, output.IssueSeverityWarning, "autoupdate of machine images is disabled")
+		}
+	}
+
+	if shoot.ClusterFeatures == nil || pointer.StringDeref(shoot.ClusterFeatures.ReversedVPN, "") != "true" {
+		add(output.AuditRuleKonnectivity, output.IssueSeverityWarning, "konnectivity is used instead of reversed-vpn")
+	}
+
+	if newestFirewallController != nil {
+		current := pointer.StringDeref(shoot.FirewallControllerVersion, "")
+		cv, err := semver.NewVersion(current)
+		// clusters without a pinned firewall controller version are updated automatically
+		if err == nil && cv.LessThan(newestFirewallController) {
+			add(output.AuditRuleFirewallControllerOutdated, output.IssueSeverityWarning, fmt.Sprintf("firewall controller %s is older than %s", current, newestFirewallController.Original()))
+		}
+	}
+
+	for _, issue := range output.ShootIssues(shoot) {
+		if issue.Type == output.IssueTypeImageExpiration {
+			add(output.AuditRuleImageExpiration, issue.Severity, issue.Message)
+		}
+	}
+
+	if pointer.StringDeref(shoot.Purpose, "") == "production" {
+		var workers int32
+		for _, w := range shoot.Workers {
+			workers += pointer.Int32Deref(w.Minimum, 0)
+		}
+		if workers <= 1 {
+			add(output.AuditRuleSingleWorkerProduction, output.IssueSeverityCritical, fmt.Sprintf("production cluster has a minimum of %d worker(s)", workers))
+		}
+	}
+
+	return findings
+}
@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/fi-ts/cloud-go/api/models"
+	"github.com/fi-ts/cloudctl/cmd/output"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/utils/pointer"
+)
+
+func Test_auditCluster(t *testing.T) {
+	compliant := &models.V1ClusterResponse{
+		ID:                        pointer.StringPtr("c1"),
+		Name:                      pointer.StringPtr("banking"),
+		Purpose:                   pointer.StringPtr("production"),
+		Kubernetes:                &models.V1Kubernetes{AllowPrivilegedContainers: pointer.BoolPtr(false)},
+		ControlPlaneFeatureGates:  []string{"clusterAudit"},
+		ClusterFeatures:           &models.V1ClusterFeatures{ReversedVPN: pointer.StringPtr("true")},
+		FirewallControllerVersion: pointer.StringPtr("v1.1.0"),
+		Maintenance: &models.V1Maintenance{
+			AutoUpdate: &models.V1MaintenanceAutoUpdate{
+				KubernetesVersion: pointer.BoolPtr(true),
+				MachineImage:      pointer.BoolPtr(true),
+			},
+		},
+		Workers: []*models.V1Worker{{Name: pointer.StringPtr("default"), Minimum: pointer.Int32Ptr(3)}},
+	}
+	newest := semver.MustParse("v1.1.0")
+	assert.Empty(t, auditCluster(compliant, newest))
+
+	violating := &models.V1ClusterResponse{
+		ID:                        pointer.StringPtr("c2"),
+		Name:                      pointer.StringPtr("shop"),
+		Purpose:                   pointer.StringPtr("production"),
+		Kubernetes:                &models.V1Kubernetes{AllowPrivilegedContainers: pointer.BoolPtr(true)},
+		ClusterFeatures:           &models.V1ClusterFeatures{ReversedVPN: pointer.StringPtr("false")},
+		FirewallControllerVersion: pointer.StringPtr("v1.0.0"),
+		Maintenance: &models.V1Maintenance{
+			AutoUpdate: &models.V1MaintenanceAutoUpdate{
+				KubernetesVersion: pointer.BoolPtr(false),
+				MachineImage:      pointer.BoolPtr(false),
+			},
+		},
+		Workers: []*models.V1Worker{{Name: pointer.StringPtr("default"), Minimum: pointer.Int32Ptr(1)}},
+	}
+	var rules []string
+	for _, f := range auditCluster(violating, newest) {
+		assert.Equal(t, "c2", f.ClusterID)
+		rules = append(rules, f.Rule)
+	}
+	assert.Equal(t, []string{
+		output.AuditRulePrivilegedAllowed,
+		output.AuditRuleAuditOff,
+		output.AuditRuleAutoUpdateKubernetesOff,
+		output.AuditRuleAutoUpdateMachineImagesOff,
+		output.AuditRuleKonnectivity,
+		output.AuditRuleFirewallControllerOutdated,
+		output.AuditRuleSingleWorkerProduction,
+	}, rules)
+
+	violating.Purpose = pointer.StringPtr("evaluation")
+	violating.FirewallControllerVersion = pointer.StringPtr("")
+	assert.Len(t, auditCluster(violating, newest), 5)
+}
+
+func Test_newestFirewallControllerVersion(t *testing.T) {
+	assert.Nil(t, newestFirewallControllerVersion(nil))
+
+	got := newestFirewallControllerVersion([]*models.V1FirewallControllerVersion{
+		{Version: pointer.StringPtr("v1.0.10")},
+		{Version: pointer.StringPtr("v1.1.2")},
+		{Version: pointer.StringPtr("auto")},
+		{Version: pointer.StringPtr("v1.0.9")},
+	})
+	require.NotNil(t, got)
+	assert.Equal(t, "v1.1.2", got.Original())
+}
+
+func Test_clusterAuditSARIF(t *testing.T) {
+	raw, err := output.ClusterAuditSARIF("cloudctl", "v0.0.0", output.ClusterAuditFindings{
+		{ClusterID: "c1", ClusterName: "shop", ProjectID: "p1", Tenant: "t1", Rule: output.AuditRulePrivilegedAllowed, Severity: output.IssueSeverityCritical, Message: "privileged containers are allowed"},
+	})
+	require.NoError(t, err)
+
+	var sarif map[string]interface{}
+	require.NoError(t, json.Unmarshal(raw, &sarif))
+	assert.Equal(t, "2.1.0", sarif["version"])
+
+	run := sarif["runs"].([]interface{})[0].(map[string]interface{})
+	rules := run["tool"].(map[string]interface{})["driver"].(map[string]interface{})["rules"].([]interface{})
+	assert.Len(t, rules, len(output.ClusterAuditRules))
+
+	result := run["results"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, output.AuditRulePrivilegedAllowed, result["ruleId"])
+	assert.Equal(t, "error", result["level"])
+	location := result["locations"].([]interface{})[0].(map[string]interface{})["logicalLocations"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, "t1/p1/c1", location["fullyQualifiedName"])
+}
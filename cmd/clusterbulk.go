@@ -0,0 +1,192 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/fatih/color"
+	"github.com/fi-ts/cloud-go/api/client/cluster"
+	"github.com/fi-ts/cloud-go/api/models"
+	"github.com/fi-ts/cloudctl/cmd/helper"
+	"github.com/fi-ts/cloudctl/cmd/output"
+	"github.com/spf13/viper"
+	"golang.org/x/sync/semaphore"
+	"k8s.io/utils/pointer"
+)
+
+const (
+	clusterBulkParallelismDefault = 5
+)
+
+// clustersBySelector returns all clusters which have the given labels.
+func (c *config) clustersBySelector(selector []string) ([]*models.V1ClusterResponse, error) {
+	labels, err := helper.LabelsToMap(selector)
+	if err != nil {
+		return nil, err
+	}
+
+	fcp := cluster.NewFindClustersParams().WithReturnMachines(pointer.BoolPtr(false))
+	fcp.SetBody(&models.V1ClusterFindRequest{Labels: labels})
+	found, err := c.cloud.Cluster.FindClusters(fcp, nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(found.Payload) == 0 {
+		return nil, fmt.Errorf("no clusters found with labels %v", selector)
+	}
+	return found.Payload, nil
+}
+
+// confirmClusterBulk lists the clusters the operation is run on and prompts the user once for all of them,
+// can be skipped with --yes-i-really-mean-it
+func confirmClusterBulk(verb string, clusters []*models.V1ClusterResponse, note func(*models.V1ClusterResponse) string) error {
+	fmt.Printf("the following %d clusters will be %s:\n", len(clusters), verb)
+	for _, shoot := range clusters {
+		line := fmt.Sprintf("  %s\t%s\t%s", pointer.StringDeref(shoot.ID, ""), pointer.StringDeref(shoot.Name, ""), pointer.StringDeref(shoot.ProjectID, ""))
+		if note != nil {
+			if n := note(shoot); n != "" {
+				line += "\t" + n
+			}
+		}
+		fmt.Println(line)
+	}
+	if viper.GetBool("yes-i-really-mean-it") {
+		return nil
+	}
+	return helper.Prompt("Are you sure? (y/n)", "y")
+}
+
+// runClusterBulk runs the operation on every given cluster with at most parallelism operations at once
+// and prints a summary of the results. an error is returned if the operation failed for any cluster.
+func runClusterBulk(clusters []*models.V1ClusterResponse, parallelism int, op func(*models.V1ClusterResponse) error) error {
+	if parallelism < 1 {
+		return fmt.Errorf("parallelism must be at least 1")
+	}
+
+	sem := semaphore.NewWeighted(int64(parallelism))
+	results := make(output.ClusterBulkResults, len(clusters))
+	var wg sync.WaitGroup
+	for i, shoot := range clusters {
+		i, shoot := i, shoot
+		err := sem.Acquire(context.Background(), 1)
+		if err != nil {
+			return err
+		}
+		wg.Add(1)
+		go func() {
+			defer sem.Release(1)
+			defer wg.Done()
+
+			result := output.ClusterBulkResult{
+				ClusterID:   pointer.StringDeref(shoot.ID, ""),
+				ClusterName: pointer.StringDeref(shoot.Name, ""),
+				ProjectID:   pointer.StringDeref(shoot.ProjectID, ""),
+				Succeeded:   true,
+			}
+			err := op(shoot)
+			if err != nil {
+				result.Succeeded = false
+				result.Error = err.Error()
+			}
+			results[i] = result
+		}()
+	}
+	wg.Wait()
+
+	err := output.New().Print(results)
+	if err != nil {
+		return err
+	}
+
+	failed := 0
+	for _, r := range results {
+		if !r.Succeeded {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("operation failed for %d of %d clusters", failed, len(results))
+	}
+	return nil
+}
+
+func (c *config) updateClusters(selector []string) error {
+	clusters, err := c.clustersBySelector(selector)
+	if err != nil {
+		return err
+	}
+
+	requests := map[string]*models.V1ClusterUpdateRequest{}
+	for _, current := range clusters {
+		cur, err := clusterUpdateRequestFromFlags(current)
+		if err != nil {
+			return fmt.Errorf("cluster %s: %w", *current.ID, err)
+		}
+		requests[*current.ID] = cur
+	}
+
+	if viper.GetBool("dry-run") {
+		for _, current := range clusters {
+			err := printClusterUpdateDryRun(current, requests[*current.ID])
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	violations, err := checkClustersUpdatePolicies(clusters, requests)
+	if err != nil {
+		return err
+	}
+
+	err = confirmClusterBulk("updated", clusters, func(current *models.V1ClusterResponse) string {
+		note := ""
+		if clusterUpdateCausesDowntime(current, requests[*current.ID]) {
+			note = "(causes downtime)"
+		}
+		for _, v := range violations[*current.ID] {
+			note += fmt.Sprintf("\n    %s violates %s", color.YellowString("⚠"), v)
+		}
+		return note
+	})
+	if err != nil {
+		return err
+	}
+
+	return runClusterBulk(clusters, viper.GetInt("parallelism"), func(current *models.V1ClusterResponse) error {
+		request := cluster.NewUpdateClusterParams()
+		request.SetBody(requests[*current.ID])
+		shoot, err := c.cloud.Cluster.UpdateCluster(request, nil)
+		if err != nil {
+			return err
+		}
+		_, err = c.waitForClusterIfRequested(shoot.Payload, clusterWaitForSucceeded, lastOperationTime(current))
+		return err
+	})
+}
+
+func (c *config) reconcileClusters(selector []string, body *models.V1ClusterReconcileRequest) error {
+	clusters, err := c.clustersBySelector(selector)
+	if err != nil {
+		return err
+	}
+
+	err = confirmClusterBulk("reconciled", clusters, nil)
+	if err != nil {
+		return err
+	}
+
+	return runClusterBulk(clusters, viper.GetInt("parallelism"), func(current *models.V1ClusterResponse) error {
+		request := cluster.NewReconcileClusterParams()
+		request.SetID(*current.ID)
+		request.Body = body
+		shoot, err := c.cloud.Cluster.ReconcileCluster(request, nil)
+		if err != nil {
+			return err
+		}
+		_, err = c.waitForClusterIfRequested(shoot.Payload, clusterWaitForSucceeded, lastOperationTime(current))
+		return err
+	})
+}
@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/fi-ts/cloud-go/api/client"
+	"github.com/fi-ts/cloud-go/api/client/cluster"
+	"github.com/fi-ts/cloud-go/api/models"
+	mockcluster "github.com/fi-ts/cloud-go/test/mocks/cluster"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"k8s.io/utils/pointer"
+)
+
+func Test_runClusterBulk(t *testing.T) {
+	var clusters []*models.V1ClusterResponse
+	for i := 0; i < 10; i++ {
+		clusters = append(clusters, &models.V1ClusterResponse{
+			ID:        pointer.StringPtr(fmt.Sprintf("c%d", i)),
+			Name:      pointer.StringPtr(fmt.Sprintf("cluster-%d", i)),
+			ProjectID: pointer.StringPtr("p1"),
+		})
+	}
+
+	var running, maxRunning int32
+	err := runClusterBulk(clusters, 3, func(shoot *models.V1ClusterResponse) error {
+		n := atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+		for {
+			m := atomic.LoadInt32(&maxRunning)
+			if n <= m || atomic.CompareAndSwapInt32(&maxRunning, m, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		if *shoot.ID == "c4" {
+			return fmt.Errorf("update failed")
+		}
+		return nil
+	})
+
+	assert.EqualError(t, err, "operation failed for 1 of 10 clusters")
+	assert.LessOrEqual(t, maxRunning, int32(3))
+	assert.Error(t, runClusterBulk(clusters, 0, func(*models.V1ClusterResponse) error { return nil }))
+}
+
+func Test_updateClustersKeepsReversedVPN(t *testing.T) {
+	useTestPolicies(t)
+	viper.Set("addlabels", []string{"env=prod"})
+	viper.Set("parallelism", 1)
+	viper.Set("yes-i-really-mean-it", true)
+
+	current := &models.V1ClusterResponse{
+		ID:        pointer.StringPtr("c1"),
+		Name:      pointer.StringPtr("shop"),
+		ProjectID: pointer.StringPtr("p1"),
+		Labels:    map[string]string{"team": "a"},
+		Maintenance: &models.V1Maintenance{
+			AutoUpdate: &models.V1MaintenanceAutoUpdate{
+				KubernetesVersion: pointer.BoolPtr(true),
+				MachineImage:      pointer.BoolPtr(true),
+			},
+		},
+		ClusterFeatures: &models.V1ClusterFeatures{ReversedVPN: pointer.StringPtr("true")},
+	}
+
+	var body *models.V1ClusterUpdateRequest
+	mockClusterService := new(mockcluster.ClientService)
+	mockClusterService.On("FindClusters", mock.Anything, mock.Anything).Return(&cluster.FindClustersOK{Payload: []*models.V1ClusterResponse{current}}, nil)
+	mockClusterService.On("UpdateCluster", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		body = args.Get(0).(*cluster.UpdateClusterParams).Body
+	}).Return(&cluster.UpdateClusterOK{Payload: current}, nil)
+	c := &config{cloud: &client.CloudAPI{Cluster: mockClusterService}}
+
+	require.NoError(t, c.updateClusters([]string{"team=a"}))
+	require.NotNil(t, body)
+	assert.Equal(t, map[string]string{"team": "a", "env": "prod"}, body.Labels)
+	assert.Nil(t, body.ClusterFeatures, "an unrelated change must not touch the reversed vpn setting")
+
+	viper.Set("reversed-vpn", false)
+	require.NoError(t, c.updateClusters([]string{"team=a"}))
+	require.NotNil(t, body.ClusterFeatures)
+	assert.Equal(t, "false", pointer.StringDeref(body.ClusterFeatures.ReversedVPN, ""))
+}
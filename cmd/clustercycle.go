@@ -0,0 +1,267 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/fi-ts/cloud-go/api/client/cluster"
+	"github.com/fi-ts/cloud-go/api/models"
+	"github.com/fi-ts/cloudctl/cmd/helper"
+	"github.com/gardener/gardener/pkg/apis/core/v1beta1"
+	"github.com/spf13/viper"
+	"k8s.io/utils/pointer"
+)
+
+const (
+	// workerGroupTag is the machine tag which holds the name of the worker group a worker belongs to
+	workerGroupTag = "worker.gardener.cloud/pool"
+)
+
+var (
+	// the shoot conditions which have to be true again before the next machine is cycled
+	clusterCycleHealthConditions = []string{
+		string(v1beta1.ShootEveryNodeReady),
+		string(v1beta1.ShootSystemComponentsHealthy),
+	}
+)
+
+// machineWorkerGroup returns the name of the worker group of the given machine or an empty string if it is unknown.
+func machineWorkerGroup(m *models.ModelsV1MachineResponse) string {
+	for _, t := range m.Tags {
+		parts := strings.SplitN(t, "=", 2)
+		if len(parts) == 2 && parts[0] == workerGroupTag {
+			return parts[1]
+		}
+	}
+	return ""
+}
+
+// clusterMachineRollAll runs the given operation, a cycle or a reset, on the workers of the cluster in batches of the
+// given parallelism. after every batch it waits until the machines of the batch are back and the nodes are ready and
+// the system components are healthy again, the first failure aborts the run. verb describes the operation in messages.
+func (c *config) clusterMachineRollAll(cid, verb string, op func(mid string) error) error {
+	workerGroup := viper.GetString("workergroup")
+	parallelism := viper.GetInt("parallelism")
+	timeout := viper.GetDuration("timeout")
+	if parallelism < 1 {
+		return fmt.Errorf("parallelism must be at least 1")
+	}
+
+	findRequest := cluster.NewFindClusterParams()
+	findRequest.SetID(cid)
+	shoot, err := c.cloud.Cluster.FindCluster(findRequest, nil)
+	if err != nil {
+		return err
+	}
+	current := shoot.Payload
+
+	if workerGroup != "" {
+		found := false
+		for _, w := range current.Workers {
+			if pointer.StringDeref(w.Name, "") == workerGroup {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("worker group %q not found in cluster:%s", workerGroup, cid)
+		}
+	}
+
+	var machines []*models.ModelsV1MachineResponse
+	for _, m := range sortedMachines(current.Machines) {
+		if workerGroup != "" && machineWorkerGroup(m) != workerGroup {
+			continue
+		}
+		machines = append(machines, m)
+	}
+	if len(machines) == 0 {
+		return fmt.Errorf("no workers to %s found in cluster:%s", verb, cid)
+	}
+
+	if !viper.GetBool("yes-i-really-mean-it") {
+		fmt.Printf("%d workers of cluster %s will be %s, %d at a time\n", len(machines), *current.Name, verbPast(verb), parallelism)
+		err = helper.Prompt("Are you sure? (y/n)", "y")
+		if err != nil {
+			return err
+		}
+	}
+
+	var done []string
+	for start := 0; start < len(machines); start += parallelism {
+		end := start + parallelism
+		if end > len(machines) {
+			end = len(machines)
+		}
+		batch := machines[start:end]
+
+		// the provisioning events of the machines before the operation tell when they are back
+		lastEvents := machineLastEvents(current, batch)
+		since := time.Now()
+		errs := make([]error, len(batch))
+		var wg sync.WaitGroup
+		for i, m := range batch {
+			i, mid := i, *m.ID
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				errs[i] = op(mid)
+			}()
+		}
+		wg.Wait()
+
+		var failed []string
+		for i, m := range batch {
+			if errs[i] != nil {
+				failed = append(failed, fmt.Sprintf("%s: %v", *m.ID, errs[i]))
+				continue
+			}
+			fmt.Fprintf(os.Stderr, "%s %s machine %s\n", time.Now().Format("15:04:05"), verbPast(verb), *m.ID)
+			done = append(done, *m.ID)
+		}
+		if len(failed) > 0 {
+			return clusterRollAbort(verb, done, machines[end:], fmt.Errorf("unable to %s machines:\n%s", verb, strings.Join(failed, "\n")))
+		}
+
+		current, err = c.waitForClusterMachinesBack(cid, lastEvents, since, timeout)
+		if err != nil {
+			return clusterRollAbort(verb, done, machines[end:], err)
+		}
+	}
+
+	fmt.Printf("%s %s %d workers of cluster %s\n", color.GreenString("✔"), verbPast(verb), len(done), cid)
+	return nil
+}
+
+// verbPast returns the past participle of the operations of a rolling run.
+func verbPast(verb string) string {
+	if verb == "cycle" {
+		return "cycled"
+	}
+	return verb
+}
+
+// clusterRollAbort reports which machines were processed and which were left untouched before the rolling run was aborted.
+func clusterRollAbort(verb string, done []string, remaining []*models.ModelsV1MachineResponse, err error) error {
+	var untouched []string
+	for _, m := range remaining {
+		untouched = append(untouched, *m.ID)
+	}
+	fmt.Fprintf(os.Stderr, "%s rolling %s aborted\n", color.RedString("✗"), verb)
+	fmt.Fprintf(os.Stderr, "%-10s %s\n", verbPast(verb)+":", strings.Join(done, " "))
+	fmt.Fprintf(os.Stderr, "%-10s %s\n", "untouched:", strings.Join(untouched, " "))
+	return err
+}
+
+// machineLastEvents returns the time of the last provisioning event of the given machines as currently known
+// in the cluster by machine id.
+func machineLastEvents(shoot *models.V1ClusterResponse, machines []*models.ModelsV1MachineResponse) map[string]string {
+	known := map[string]string{}
+	for _, m := range shoot.Machines {
+		if m.Events != nil {
+			known[*m.ID] = m.Events.LastEventTime
+		}
+	}
+	lastEvents := map[string]string{}
+	for _, m := range machines {
+		lastEvents[*m.ID] = known[*m.ID]
+	}
+	return lastEvents
+}
+
+// waitForClusterMachinesBack polls the cluster until clusterMachinesBack is true and returns the cluster in this state.
+func (c *config) waitForClusterMachinesBack(cid string, lastEvents map[string]string, since time.Time, timeout time.Duration) (*models.V1ClusterResponse, error) {
+	if timeout <= 0 {
+		timeout = clusterWaitTimeoutDefault
+	}
+	deadline := time.Now().Add(timeout)
+	lastStatus := ""
+	for {
+		time.Sleep(clusterWaitPollInterval)
+
+		findRequest := cluster.NewFindClusterParams().WithID(cid)
+		resp, err := c.cloud.Cluster.FindCluster(findRequest, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		status := clusterWaitStatus(resp.Payload)
+		if status != lastStatus {
+			fmt.Fprintf(os.Stderr, "%s %s\n", time.Now().Format("15:04:05"), status)
+			lastStatus = status
+		}
+
+		if clusterMachinesBack(resp.Payload, lastEvents, since) {
+			return resp.Payload, nil
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("timeout of %s exceeded while waiting for the nodes of cluster %s to become healthy", timeout, cid)
+		}
+	}
+}
+
+// clusterMachinesBack returns true if all health conditions are true and the machines of the batch, given with the time
+// of their last provisioning event before the operation, have been replaced. a machine counts as replaced when it is gone
+// from the cluster or has a new provisioning event, i.e. it booted again. checking the machines is required as a
+// replacement can join before the old node is gone, or the node readiness can drop and recover between two polls, so
+// there is not necessarily a transition. a transition of the node readiness after since is taken as additional signal.
+func clusterMachinesBack(shoot *models.V1ClusterResponse, lastEvents map[string]string, since time.Time) bool {
+	if !clusterNodesHealthy(shoot) {
+		return false
+	}
+	if clusterNodesBecameReadySince(shoot, since) {
+		return true
+	}
+	current := map[string]*models.ModelsV1MachineResponse{}
+	for _, m := range shoot.Machines {
+		current[*m.ID] = m
+	}
+	for id, lastEvent := range lastEvents {
+		m, ok := current[id]
+		if !ok {
+			continue
+		}
+		if m.Events == nil || m.Events.LastEventTime == lastEvent {
+			return false
+		}
+	}
+	return true
+}
+
+// clusterNodesHealthy returns true if all health conditions of the cluster are true.
+func clusterNodesHealthy(shoot *models.V1ClusterResponse) bool {
+	if shoot.Status == nil {
+		return false
+	}
+	conditions := map[string]string{}
+	for _, condition := range shoot.Status.Conditions {
+		conditions[pointer.StringDeref(condition.Type, "")] = pointer.StringDeref(condition.Status, "")
+	}
+	for _, t := range clusterCycleHealthConditions {
+		if conditions[t] != string(v1beta1.ConditionTrue) {
+			return false
+		}
+	}
+	return true
+}
+
+// clusterNodesBecameReadySince returns true if the node readiness of the cluster changed to true after since. the last
+// update time of a condition is refreshed on every health check even if its status did not change, so only the
+// transition time is taken into account.
+func clusterNodesBecameReadySince(shoot *models.V1ClusterResponse, since time.Time) bool {
+	if shoot.Status == nil {
+		return false
+	}
+	for _, condition := range shoot.Status.Conditions {
+		if pointer.StringDeref(condition.Type, "") != string(v1beta1.ShootEveryNodeReady) || pointer.StringDeref(condition.Status, "") != string(v1beta1.ConditionTrue) {
+			continue
+		}
+		transition, err := time.Parse(time.RFC3339, pointer.StringDeref(condition.LastTransitionTime, ""))
+		return err == nil && transition.After(since)
+	}
+	return false
+}
@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fi-ts/cloud-go/api/models"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/utils/pointer"
+)
+
+func Test_clusterMachinesBack(t *testing.T) {
+	now := time.Now()
+	machine := func(id, lastEvent string) *models.ModelsV1MachineResponse {
+		return &models.ModelsV1MachineResponse{ID: pointer.StringPtr(id), Events: &models.ModelsV1MachineRecentProvisioningEvents{LastEventTime: lastEvent}}
+	}
+	shoot := func(status string, transition, updated time.Time, machines ...*models.ModelsV1MachineResponse) *models.V1ClusterResponse {
+		var conditions []*models.V1beta1Condition
+		for _, ct := range clusterCycleHealthConditions {
+			conditions = append(conditions, &models.V1beta1Condition{
+				Type:               pointer.StringPtr(ct),
+				Status:             pointer.StringPtr(status),
+				LastTransitionTime: pointer.StringPtr(transition.Format(time.RFC3339)),
+				LastUpdateTime:     pointer.StringPtr(updated.Format(time.RFC3339)),
+			})
+		}
+		return &models.V1ClusterResponse{
+			ID:       pointer.StringPtr("c1"),
+			Status:   &models.V1beta1ShootStatus{Conditions: conditions},
+			Machines: machines,
+		}
+	}
+	// m1 was cycled, its last provisioning event before the cycle was at 10:00
+	lastEvents := map[string]string{"m1": "2021-11-04T10:00:00Z"}
+
+	tests := []struct {
+		name  string
+		shoot *models.V1ClusterResponse
+		want  bool
+	}{
+		{
+			name:  "machine booted again without a transition of the node readiness",
+			shoot: shoot("True", now.Add(-time.Hour), now.Add(time.Minute), machine("m1", "2021-11-04T10:05:00Z"), machine("m2", "")),
+			want:  true,
+		},
+		{
+			name:  "machine was replaced",
+			shoot: shoot("True", now.Add(-time.Hour), now.Add(time.Minute), machine("m3", "2021-11-04T10:05:00Z"), machine("m2", "")),
+			want:  true,
+		},
+		{
+			name:  "nodes became ready after the operation",
+			shoot: shoot("True", now.Add(2*time.Minute), now.Add(3*time.Minute), machine("m1", "2021-11-04T10:00:00Z")),
+			want:  true,
+		},
+		{
+			name:  "machine still has no new event and the readiness did not change",
+			shoot: shoot("True", now.Add(-time.Hour), now.Add(time.Minute), machine("m1", "2021-11-04T10:00:00Z")),
+			want:  false,
+		},
+		{
+			name:  "machine is back but the nodes are not ready",
+			shoot: shoot("False", now.Add(time.Minute), now.Add(time.Minute), machine("m1", "2021-11-04T10:05:00Z")),
+			want:  false,
+		},
+		{
+			name:  "no status",
+			shoot: &models.V1ClusterResponse{ID: pointer.StringPtr("c1")},
+			want:  false,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, clusterMachinesBack(tt.shoot, lastEvents, now))
+		})
+	}
+}
+
+func Test_machineLastEvents(t *testing.T) {
+	m1 := &models.ModelsV1MachineResponse{ID: pointer.StringPtr("m1"), Events: &models.ModelsV1MachineRecentProvisioningEvents{LastEventTime: "2021-11-04T10:00:00Z"}}
+	m2 := &models.ModelsV1MachineResponse{ID: pointer.StringPtr("m2")}
+	shoot := &models.V1ClusterResponse{Machines: []*models.ModelsV1MachineResponse{m1, m2}}
+
+	assert.Equal(t, map[string]string{"m1": "2021-11-04T10:00:00Z", "m2": ""}, machineLastEvents(shoot, []*models.ModelsV1MachineResponse{m1, m2}))
+}
+
+func Test_machineWorkerGroup(t *testing.T) {
+	m := &models.ModelsV1MachineResponse{
+		ID:   pointer.StringPtr("m1"),
+		Tags: []string{"kubernetes.io/cluster=c1", "worker.gardener.cloud/pool=group-1"},
+	}
+	assert.Equal(t, "group-1", machineWorkerGroup(m))
+	assert.Equal(t, "", machineWorkerGroup(&models.ModelsV1MachineResponse{ID: pointer.StringPtr("m2")}))
+}
@@ -0,0 +1,223 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/fi-ts/cloud-go/api/models"
+	"gopkg.in/yaml.v3"
+)
+
+// the fields of an update request which require the firewall to be recreated
+var clusterDowntimeFields = map[string]bool{
+	"FirewallImage":      true,
+	"FirewallSize":       true,
+	"AdditionalNetworks": true,
+}
+
+type clusterFieldChange struct {
+	Field    string
+	Before   *string
+	After    *string
+	Downtime bool
+}
+
+func (c clusterFieldChange) String() string {
+	marker := ""
+	if c.Downtime {
+		marker = color.RedString(" (causes downtime)")
+	}
+	switch {
+	case c.Before == nil:
+		return color.GreenString("+ %s: %s", c.Field, *c.After) + marker
+	case c.After == nil:
+		return color.RedString("- %s: %s", c.Field, *c.Before) + marker
+	default:
+		return color.YellowString("~ %s: %s → %s", c.Field, *c.Before, *c.After) + marker
+	}
+}
+
+// printClusterUpdateDryRun prints the update request and the changes it would apply to the current cluster
+func printClusterUpdateDryRun(current *models.V1ClusterResponse, cur *models.V1ClusterUpdateRequest) error {
+	content, err := yaml.Marshal(cur)
+	if err != nil {
+		return err
+	}
+	fmt.Println("Request:")
+	fmt.Println(string(content))
+
+	changes, err := clusterUpdateDiff(current, cur)
+	if err != nil {
+		return err
+	}
+	fmt.Println("Changes:")
+	if len(changes) == 0 {
+		fmt.Println("no changes")
+		return nil
+	}
+	for _, change := range changes {
+		fmt.Println(change.String())
+	}
+	return nil
+}
+
+// clusterUpdateDiff compares every field which is set in the update request with the current state of the cluster.
+// fields which are not set in the request are left untouched by the api and therefore not compared.
+func clusterUpdateDiff(current *models.V1ClusterResponse, cur *models.V1ClusterUpdateRequest) ([]clusterFieldChange, error) {
+	before := clusterUpdateRequestFromCreateRequest(current, clusterCreateRequestFromResponse(current))
+	if current.Status != nil && current.Status.SeedName != "" {
+		before.SeedName = &current.Status.SeedName
+	}
+
+	beforeFields, err := flattenClusterUpdateRequest(before)
+	if err != nil {
+		return nil, err
+	}
+	afterFields, err := flattenClusterUpdateRequest(cur)
+	if err != nil {
+		return nil, err
+	}
+
+	paths := map[string]bool{}
+	for p := range beforeFields {
+		paths[p] = true
+	}
+	for p := range afterFields {
+		paths[p] = true
+	}
+
+	var changes []clusterFieldChange
+	for p := range paths {
+		topLevel := strings.SplitN(strings.SplitN(p, ".", 2)[0], "[", 2)[0]
+		if !afterFieldSet(afterFields, topLevel) {
+			continue
+		}
+		b, bok := beforeFields[p]
+		a, aok := afterFields[p]
+		if bok && aok && a == b {
+			continue
+		}
+		if bok && !aok && !replacedAsWhole(p) {
+			// unset fields of nested objects are not changed by the api
+			continue
+		}
+		if (bok && !aok && isEmptyPlaceholder(b) && hasChildren(afterFields, p)) ||
+			(aok && !bok && isEmptyPlaceholder(a) && hasChildren(beforeFields, p)) {
+			// an empty list or map on one side is already reflected by the changes of its children
+			continue
+		}
+		change := clusterFieldChange{
+			Field:    p,
+			Downtime: clusterDowntimeFields[topLevel],
+		}
+		if bok {
+			b := b
+			change.Before = &b
+		}
+		if aok {
+			a := a
+			change.After = &a
+		}
+		changes = append(changes, change)
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Field < changes[j].Field
+	})
+	return changes, nil
+}
+
+// afterFieldSet returns true if the top level field or any of its children is set in the request
+func afterFieldSet(fields map[string]string, topLevel string) bool {
+	for p := range fields {
+		if p == topLevel || strings.HasPrefix(p, topLevel+".") || strings.HasPrefix(p, topLevel+"[") {
+			return true
+		}
+	}
+	return false
+}
+
+// replacedAsWhole returns true if the given path belongs to a list or label map, these are sent completely
+// in the update request so entries which are missing in the request are removed.
+func replacedAsWhole(path string) bool {
+	return strings.Contains(path, "[") || strings.HasPrefix(path, "Labels.")
+}
+
+func isEmptyPlaceholder(value string) bool {
+	return value == "[]" || value == "{}"
+}
+
+func hasChildren(fields map[string]string, path string) bool {
+	for p := range fields {
+		if strings.HasPrefix(p, path+".") || strings.HasPrefix(p, path+"[") {
+			return true
+		}
+	}
+	return false
+}
+
+// flattenClusterUpdateRequest returns all leaf values of the request by their path,
+// list entries are identified by their name or network instead of their index to get a stable diff.
+func flattenClusterUpdateRequest(cur *models.V1ClusterUpdateRequest) (map[string]string, error) {
+	// the id is not part of the diff
+	withoutID := *cur
+	withoutID.ID = nil
+
+	b, err := json.Marshal(withoutID)
+	if err != nil {
+		return nil, err
+	}
+	var generic interface{}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	err = dec.Decode(&generic)
+	if err != nil {
+		return nil, err
+	}
+
+	result := map[string]string{}
+	flatten("", generic, result)
+	return result, nil
+}
+
+func flatten(prefix string, value interface{}, result map[string]string) {
+	switch v := value.(type) {
+	case nil:
+	case map[string]interface{}:
+		if len(v) == 0 && prefix != "" {
+			result[prefix] = "{}"
+		}
+		for key, child := range v {
+			path := key
+			if prefix != "" {
+				path = prefix + "." + key
+			}
+			flatten(path, child, result)
+		}
+	case []interface{}:
+		if len(v) == 0 {
+			result[prefix] = "[]"
+		}
+		for i, child := range v {
+			key := fmt.Sprintf("%d", i)
+			if m, ok := child.(map[string]interface{}); ok {
+				for _, idField := range []string{"Name", "NetworkID"} {
+					if id, ok := m[idField].(string); ok && id != "" {
+						key = id
+						break
+					}
+				}
+			} else if s, ok := child.(string); ok {
+				// scalar lists like networks and ips are compared as sets
+				key = s
+			}
+			flatten(fmt.Sprintf("%s[%s]", prefix, key), child, result)
+		}
+	default:
+		result[prefix] = fmt.Sprintf("%v", v)
+	}
+}
@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/fi-ts/cloud-go/api/models"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/utils/pointer"
+)
+
+func Test_clusterUpdateDiff(t *testing.T) {
+	current := &models.V1ClusterResponse{
+		ID:                 pointer.StringPtr("c1"),
+		Name:               pointer.StringPtr("cluster"),
+		FirewallImage:      pointer.StringPtr("firewall-2.0"),
+		FirewallSize:       pointer.StringPtr("c1-xlarge-x86"),
+		AdditionalNetworks: []string{"internet"},
+		Labels:             map[string]string{"team": "a", "stage": "dev"},
+		Kubernetes:         &models.V1Kubernetes{Version: pointer.StringPtr("1.21.5")},
+		Workers: []*models.V1Worker{
+			{
+				Name:    pointer.StringPtr("default"),
+				Minimum: pointer.Int32Ptr(1),
+				Maximum: pointer.Int32Ptr(2),
+			},
+		},
+		EgressRules: []*models.V1EgressRule{
+			{NetworkID: pointer.StringPtr("internet"), IPs: []string{"1.2.3.4"}},
+		},
+	}
+
+	tests := []struct {
+		name string
+		cur  *models.V1ClusterUpdateRequest
+		want []string
+	}{
+		{
+			name: "nothing set",
+			cur: &models.V1ClusterUpdateRequest{
+				ID:         pointer.StringPtr("c1"),
+				Kubernetes: &models.V1Kubernetes{},
+			},
+			want: nil,
+		},
+		{
+			name: "firewall image and worker size",
+			cur: &models.V1ClusterUpdateRequest{
+				ID:            pointer.StringPtr("c1"),
+				FirewallImage: pointer.StringPtr("firewall-2.1"),
+				Workers: []*models.V1Worker{
+					{
+						Name:    pointer.StringPtr("default"),
+						Minimum: pointer.Int32Ptr(1),
+						Maximum: pointer.Int32Ptr(4),
+					},
+				},
+			},
+			want: []string{
+				"~ FirewallImage: firewall-2.0 → firewall-2.1 (causes downtime)",
+				"~ Workers[default].Maximum: 2 → 4",
+			},
+		},
+		{
+			name: "labels and egress rules are replaced",
+			cur: &models.V1ClusterUpdateRequest{
+				ID:          pointer.StringPtr("c1"),
+				Labels:      map[string]string{"team": "b"},
+				EgressRules: []*models.V1EgressRule{},
+			},
+			want: []string{
+				"- EgressRules[internet].IPs[1.2.3.4]: 1.2.3.4",
+				"- EgressRules[internet].NetworkID: internet",
+				"- Labels.stage: dev",
+				"~ Labels.team: a → b",
+			},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			changes, err := clusterUpdateDiff(current, tt.cur)
+			assert.NoError(t, err)
+
+			var got []string
+			for _, c := range changes {
+				got = append(got, c.String())
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
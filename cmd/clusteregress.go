@@ -0,0 +1,261 @@
+package cmd
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/fi-ts/cloud-go/api/client/cluster"
+	"github.com/fi-ts/cloud-go/api/client/ip"
+	"github.com/fi-ts/cloud-go/api/models"
+	"github.com/fi-ts/cloudctl/cmd/output"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"k8s.io/utils/pointer"
+)
+
+func newClusterEgressCmd(c *config) *cobra.Command {
+	egressCmd := &cobra.Command{
+		Use:   "egress",
+		Short: "manage the static egress ips of a cluster",
+	}
+	egressListCmd := &cobra.Command{
+		Use:     "list <clusterid>",
+		Aliases: []string{"ls"},
+		Short:   "list the egress rules of a cluster",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return c.clusterEgressList(args)
+		},
+		ValidArgsFunction: c.comp.ClusterListCompletion,
+		PreRun:            bindPFlags,
+	}
+	egressAddCmd := &cobra.Command{
+		Use:   "add <clusterid>",
+		Short: "add static egress ips to a cluster, existing egress rules are kept",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return c.clusterEgressAdd(args)
+		},
+		ValidArgsFunction: c.comp.ClusterListCompletion,
+		PreRun:            bindPFlags,
+	}
+	egressRemoveCmd := &cobra.Command{
+		Use:     "remove <clusterid>",
+		Aliases: []string{"rm", "delete"},
+		Short:   "remove static egress ips from a cluster, other egress rules are kept",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return c.clusterEgressRemove(args)
+		},
+		ValidArgsFunction: c.comp.ClusterListCompletion,
+		PreRun:            bindPFlags,
+	}
+
+	egressAddCmd.Flags().StringSlice("egress", []string{}, "static egress ip to add in the form <network>:<ip>, the ip must be a static ip of the project of the cluster, e.g.: --egress internet:1.2.3.4 --egress extnet:123.1.1.1 [required]")
+	must(egressAddCmd.MarkFlagRequired("egress"))
+	egressRemoveCmd.Flags().StringSlice("egress", []string{}, "static egress ip to remove in the form <network>:<ip>, e.g.: --egress internet:1.2.3.4 [required]")
+	must(egressRemoveCmd.MarkFlagRequired("egress"))
+
+	egressCmd.AddCommand(egressListCmd)
+	egressCmd.AddCommand(egressAddCmd)
+	egressCmd.AddCommand(egressRemoveCmd)
+
+	return egressCmd
+}
+
+// egressIP is a single ip of an egress rule
+type egressIP struct {
+	network string
+	ip      string
+}
+
+// parseEgressIPs parses egress ips in the form <network>:<ip>
+func parseEgressIPs(egress []string) ([]egressIP, error) {
+	var result []egressIP
+	for _, e := range egress {
+		parts := strings.Split(e, ":")
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("egress config needs format <network>:<ip> but got %q", e)
+		}
+		n, i := strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
+		if net.ParseIP(i) == nil {
+			return nil, fmt.Errorf("egress config contains an invalid IP %s for network %s", i, n)
+		}
+		result = append(result, egressIP{network: n, ip: i})
+	}
+	return result, nil
+}
+
+func (c *config) clusterEgressList(args []string) error {
+	ci, err := c.clusterID("egress list", args)
+	if err != nil {
+		return err
+	}
+	current, err := c.findClusterWithoutMachines(ci)
+	if err != nil {
+		return err
+	}
+	return output.New().Print(current.EgressRules)
+}
+
+func (c *config) clusterEgressAdd(args []string) error {
+	ci, err := c.clusterID("egress add", args)
+	if err != nil {
+		return err
+	}
+	ips, err := parseEgressIPs(viper.GetStringSlice("egress"))
+	if err != nil {
+		return err
+	}
+	current, err := c.findClusterWithoutMachines(ci)
+	if err != nil {
+		return err
+	}
+
+	for _, e := range ips {
+		err = c.validateEgressIP(pointer.StringDeref(current.ProjectID, ""), e)
+		if err != nil {
+			return err
+		}
+	}
+
+	rules, err := addEgressIPs(current.EgressRules, ips)
+	if err != nil {
+		return err
+	}
+	return c.updateClusterEgressRules(current, rules)
+}
+
+func (c *config) clusterEgressRemove(args []string) error {
+	ci, err := c.clusterID("egress remove", args)
+	if err != nil {
+		return err
+	}
+	ips, err := parseEgressIPs(viper.GetStringSlice("egress"))
+	if err != nil {
+		return err
+	}
+	current, err := c.findClusterWithoutMachines(ci)
+	if err != nil {
+		return err
+	}
+
+	rules, err := removeEgressIPs(current.EgressRules, ips)
+	if err != nil {
+		return err
+	}
+	return c.updateClusterEgressRules(current, rules)
+}
+
+// validateEgressIP checks that the given ip is a static ip of the project in the given network
+func (c *config) validateEgressIP(project string, e egressIP) error {
+	params := ip.NewFindIPsParams()
+	params.SetBody(&models.V1IPFindRequest{
+		IPAddress: &e.ip,
+		ProjectID: &project,
+	})
+	resp, err := c.cloud.IP.FindIPs(params, nil)
+	if err != nil {
+		return err
+	}
+	for _, i := range resp.Payload {
+		if pointer.StringDeref(i.Networkid, "") != e.network {
+			continue
+		}
+		if pointer.StringDeref(i.Type, "") != "static" {
+			return fmt.Errorf("ip %s is not static, use \"cloudctl ip static %s\" to make it static", e.ip, e.ip)
+		}
+		return nil
+	}
+	return fmt.Errorf("ip %s is not allocated in network %s of project %s", e.ip, e.network, project)
+}
+
+// addEgressIPs returns a copy of the given egress rules with the ips added, adding an existing ip is an error
+func addEgressIPs(rules []*models.V1EgressRule, ips []egressIP) ([]*models.V1EgressRule, error) {
+	result := copyEgressRules(rules)
+	for _, e := range ips {
+		var rule *models.V1EgressRule
+		for _, r := range result {
+			if pointer.StringDeref(r.NetworkID, "") == e.network {
+				rule = r
+				break
+			}
+		}
+		if rule == nil {
+			network := e.network
+			rule = &models.V1EgressRule{NetworkID: &network}
+			result = append(result, rule)
+		}
+		for _, existing := range rule.IPs {
+			if existing == e.ip {
+				return nil, fmt.Errorf("egress ip %s of network %s already exists", e.ip, e.network)
+			}
+		}
+		rule.IPs = append(rule.IPs, e.ip)
+	}
+	return result, nil
+}
+
+// removeEgressIPs returns a copy of the given egress rules without the ips, rules without ips are removed,
+// removing an ip which does not exist is an error
+func removeEgressIPs(rules []*models.V1EgressRule, ips []egressIP) ([]*models.V1EgressRule, error) {
+	result := copyEgressRules(rules)
+	for _, e := range ips {
+		found := false
+		for _, r := range result {
+			if pointer.StringDeref(r.NetworkID, "") != e.network {
+				continue
+			}
+			var remaining []string
+			for _, existing := range r.IPs {
+				if existing == e.ip {
+					found = true
+					continue
+				}
+				remaining = append(remaining, existing)
+			}
+			r.IPs = remaining
+		}
+		if !found {
+			return nil, fmt.Errorf("egress ip %s of network %s does not exist", e.ip, e.network)
+		}
+	}
+
+	// an empty list removes all egress rules, so the result must not be nil
+	remaining := []*models.V1EgressRule{}
+	for _, r := range result {
+		if len(r.IPs) > 0 {
+			remaining = append(remaining, r)
+		}
+	}
+	return remaining, nil
+}
+
+func copyEgressRules(rules []*models.V1EgressRule) []*models.V1EgressRule {
+	var result []*models.V1EgressRule
+	for _, r := range rules {
+		rule := *r
+		rule.IPs = append([]string{}, r.IPs...)
+		result = append(result, &rule)
+	}
+	return result
+}
+
+// updateClusterEgressRules replaces the egress rules of the cluster with the given rules
+func (c *config) updateClusterEgressRules(current *models.V1ClusterResponse, rules []*models.V1EgressRule) error {
+	cur := &models.V1ClusterUpdateRequest{
+		ID:          current.ID,
+		EgressRules: rules,
+		Kubernetes:  &models.V1Kubernetes{},
+	}
+	err := enforceClusterUpdatePolicies(current, cur)
+	if err != nil {
+		return err
+	}
+
+	params := cluster.NewUpdateClusterParams()
+	params.SetBody(cur)
+	shoot, err := c.cloud.Cluster.UpdateCluster(params, nil)
+	if err != nil {
+		return err
+	}
+	return output.New().Print(shoot.Payload.EgressRules)
+}
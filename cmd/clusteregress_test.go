@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/fi-ts/cloud-go/api/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/utils/pointer"
+)
+
+func Test_parseEgressIPs(t *testing.T) {
+	ips, err := parseEgressIPs([]string{"internet:1.2.3.4", " extnet : 10.0.0.1 "})
+	require.NoError(t, err)
+	assert.Equal(t, []egressIP{{network: "internet", ip: "1.2.3.4"}, {network: "extnet", ip: "10.0.0.1"}}, ips)
+
+	_, err = parseEgressIPs([]string{"internet"})
+	assert.EqualError(t, err, `egress config needs format <network>:<ip> but got "internet"`)
+
+	_, err = parseEgressIPs([]string{"internet:1.2.3"})
+	assert.EqualError(t, err, "egress config contains an invalid IP 1.2.3 for network internet")
+}
+
+func Test_addEgressIPs(t *testing.T) {
+	rules := []*models.V1EgressRule{
+		{NetworkID: pointer.StringPtr("internet"), IPs: []string{"1.2.3.4"}},
+	}
+
+	got, err := addEgressIPs(rules, []egressIP{
+		{network: "internet", ip: "1.2.3.5"},
+		{network: "extnet", ip: "10.0.0.1"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []*models.V1EgressRule{
+		{NetworkID: pointer.StringPtr("internet"), IPs: []string{"1.2.3.4", "1.2.3.5"}},
+		{NetworkID: pointer.StringPtr("extnet"), IPs: []string{"10.0.0.1"}},
+	}, got)
+	assert.Equal(t, []string{"1.2.3.4"}, rules[0].IPs, "the given rules must not be modified")
+
+	_, err = addEgressIPs(rules, []egressIP{{network: "internet", ip: "1.2.3.4"}})
+	assert.EqualError(t, err, "egress ip 1.2.3.4 of network internet already exists")
+}
+
+func Test_removeEgressIPs(t *testing.T) {
+	rules := []*models.V1EgressRule{
+		{NetworkID: pointer.StringPtr("internet"), IPs: []string{"1.2.3.4", "1.2.3.5"}},
+		{NetworkID: pointer.StringPtr("extnet"), IPs: []string{"10.0.0.1"}},
+	}
+
+	got, err := removeEgressIPs(rules, []egressIP{{network: "internet", ip: "1.2.3.4"}})
+	require.NoError(t, err)
+	assert.Equal(t, []*models.V1EgressRule{
+		{NetworkID: pointer.StringPtr("internet"), IPs: []string{"1.2.3.5"}},
+		{NetworkID: pointer.StringPtr("extnet"), IPs: []string{"10.0.0.1"}},
+	}, got)
+	assert.Equal(t, []string{"1.2.3.4", "1.2.3.5"}, rules[0].IPs, "the given rules must not be modified")
+
+	got, err = removeEgressIPs(rules, []egressIP{
+		{network: "internet", ip: "1.2.3.4"},
+		{network: "internet", ip: "1.2.3.5"},
+		{network: "extnet", ip: "10.0.0.1"},
+	})
+	require.NoError(t, err)
+	assert.NotNil(t, got)
+	assert.Empty(t, got)
+
+	_, err = removeEgressIPs(rules, []egressIP{{network: "extnet", ip: "1.2.3.4"}})
+	assert.EqualError(t, err, "egress ip 1.2.3.4 of network extnet does not exist")
+}
@@ -0,0 +1,153 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/fi-ts/cloud-go/api/models"
+	"github.com/fi-ts/cloudctl/cmd/helper"
+	"github.com/fi-ts/cloudctl/cmd/output"
+	"github.com/spf13/viper"
+	"golang.org/x/term"
+	"k8s.io/utils/pointer"
+)
+
+const (
+	// hoursPerMonth is the average amount of hours of a month
+	hoursPerMonth = 730
+)
+
+// machineTypePrice contains either the size of a machine type, which is priced with the costs-cpu-hour,
+// costs-memory-gi-hour and costs-storage-gi-hour knobs, or its fixed price per hour.
+type machineTypePrice struct {
+	CPU     float64 `mapstructure:"cpu"`
+	Memory  float64 `mapstructure:"memory"`
+	Storage float64 `mapstructure:"storage"`
+	Hour    float64 `mapstructure:"hour"`
+}
+
+// priceTable contains the prices used to estimate the costs of a cluster
+type priceTable struct {
+	CPUHour       float64
+	MemoryGiHour  float64
+	StorageGiHour float64
+	IPHour        float64
+	MachineTypes  map[string]machineTypePrice
+}
+
+// priceTableFromConfig reads the price table from the costs-* knobs of the configuration, the machine types are
+// configured with costs-machine-types in the config file, see the example of cluster estimate.
+func priceTableFromConfig() (*priceTable, error) {
+	p := &priceTable{
+		CPUHour:       viper.GetFloat64("costs-cpu-hour"),
+		MemoryGiHour:  viper.GetFloat64("costs-memory-gi-hour"),
+		StorageGiHour: viper.GetFloat64("costs-storage-gi-hour"),
+		IPHour:        viper.GetFloat64("costs-ip-hour"),
+		MachineTypes:  map[string]machineTypePrice{},
+	}
+	err := viper.UnmarshalKey("costs-machine-types", &p.MachineTypes)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read price table from costs-machine-types: %w", err)
+	}
+	return p, nil
+}
+
+// machineHour returns the price per hour of the given machine type
+func (p *priceTable) machineHour(machineType string) (float64, error) {
+	if machineType == "" {
+		return 0, fmt.Errorf("machine type is not set and defaulted by the api, it has to be given to estimate the costs")
+	}
+	m, ok := p.MachineTypes[machineType]
+	if !ok {
+		return 0, fmt.Errorf("machine type %s is not contained in the price table costs-machine-types", machineType)
+	}
+	if m.Hour > 0 {
+		return m.Hour, nil
+	}
+	return m.CPU*p.CPUHour + m.Memory*p.MemoryGiHour + m.Storage*p.StorageGiHour, nil
+}
+
+// estimateClusterCosts returns the expected monthly costs of a cluster with the given firewall, worker groups and egress rules.
+func estimateClusterCosts(p *priceTable, name, firewallType string, workers []*models.V1Worker, egressRules []*models.V1EgressRule) (*output.ClusterCostEstimate, error) {
+	var items []output.ClusterCostEstimateItem
+
+	hour, err := p.machineHour(firewallType)
+	if err != nil {
+		return nil, fmt.Errorf("firewall: %w", err)
+	}
+	items = append(items, output.ClusterCostEstimateItem{Item: "Firewall", Type: firewallType, Min: 1, Max: 1, HourlyUnit: hour})
+
+	for _, w := range workers {
+		machineType := pointer.StringDeref(w.MachineType, "")
+		hour, err := p.machineHour(machineType)
+		if err != nil {
+			return nil, fmt.Errorf("worker group %s: %w", pointer.StringDeref(w.Name, ""), err)
+		}
+		items = append(items, output.ClusterCostEstimateItem{
+			Item:       "Worker Group " + pointer.StringDeref(w.Name, "default"),
+			Type:       machineType,
+			Min:        pointer.Int32Deref(w.Minimum, 0),
+			Max:        pointer.Int32Deref(w.Maximum, 0),
+			HourlyUnit: hour,
+		})
+	}
+
+	var ips int32
+	for _, r := range egressRules {
+		ips += int32(len(r.IPs))
+	}
+	if ips > 0 {
+		items = append(items, output.ClusterCostEstimateItem{Item: "Static Egress IPs", Min: ips, Max: ips, HourlyUnit: p.IPHour})
+	}
+
+	result := &output.ClusterCostEstimate{ClusterName: name}
+	for _, i := range items {
+		i.MinMonthly = float64(i.Min) * i.HourlyUnit * hoursPerMonth
+		i.MaxMonthly = float64(i.Max) * i.HourlyUnit * hoursPerMonth
+		result.MinMonthly += i.MinMonthly
+		result.MaxMonthly += i.MaxMonthly
+		result.Items = append(result.Items, i)
+	}
+	return result, nil
+}
+
+// clusterEstimate prints the expected monthly costs of an existing cluster
+func (c *config) clusterEstimate(args []string) error {
+	ci, err := c.clusterID("estimate", args)
+	if err != nil {
+		return err
+	}
+	current, err := c.findClusterWithoutMachines(ci)
+	if err != nil {
+		return err
+	}
+	p, err := priceTableFromConfig()
+	if err != nil {
+		return err
+	}
+	estimate, err := estimateClusterCosts(p, pointer.StringDeref(current.Name, ""), pointer.StringDeref(current.FirewallSize, ""), current.Workers, current.EgressRules)
+	if err != nil {
+		return err
+	}
+	return output.New().Print(estimate)
+}
+
+// confirmClusterCreate shows the expected monthly costs of the cluster and prompts the user if a price table is configured
+// and cloudctl runs in a terminal, so scripts creating clusters are not blocked. Can be skipped with --yes-i-really-mean-it.
+func confirmClusterCreate(scr *models.V1ClusterCreateRequest) error {
+	if viper.GetBool("yes-i-really-mean-it") || !viper.IsSet("costs-machine-types") || !term.IsTerminal(int(os.Stdin.Fd())) {
+		return nil
+	}
+	p, err := priceTableFromConfig()
+	if err == nil {
+		var estimate *output.ClusterCostEstimate
+		estimate, err = estimateClusterCosts(p, pointer.StringDeref(scr.Name, ""), pointer.StringDeref(scr.FirewallSize, ""), scr.Workers, scr.EgressRules)
+		if err == nil {
+			fmt.Printf("cluster %s is expected to cost %s per month\n", pointer.StringDeref(scr.Name, ""), output.CostRange(estimate.MinMonthly, estimate.MaxMonthly))
+		}
+	}
+	if err != nil {
+		fmt.Printf("unable to estimate the monthly costs of cluster %s: %v\n", pointer.StringDeref(scr.Name, ""), err)
+	}
+	return helper.Prompt("Are you sure? (y/n)", "y")
+}
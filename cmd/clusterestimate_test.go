@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/fi-ts/cloud-go/api/models"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/utils/pointer"
+)
+
+func Test_estimateClusterCosts(t *testing.T) {
+	p := &priceTable{
+		CPUHour:       0.01,
+		MemoryGiHour:  0.005,
+		StorageGiHour: 0.001,
+		IPHour:        0.01,
+		MachineTypes: map[string]machineTypePrice{
+			"c1-xlarge-x86": {CPU: 32, Memory: 128, Storage: 100},
+			"s2-xlarge-x86": {Hour: 0.5},
+		},
+	}
+	workers := []*models.V1Worker{
+		{Name: pointer.StringPtr("default"), MachineType: pointer.StringPtr("c1-xlarge-x86"), Minimum: pointer.Int32Ptr(1), Maximum: pointer.Int32Ptr(3)},
+	}
+	egress := []*models.V1EgressRule{
+		{NetworkID: pointer.StringPtr("internet"), IPs: []string{"1.2.3.4", "1.2.3.5"}},
+	}
+
+	got, err := estimateClusterCosts(p, "test", "s2-xlarge-x86", workers, egress)
+	require.NoError(t, err)
+	require.Len(t, got.Items, 3)
+
+	// firewall: 0.5 per hour
+	assert.InDelta(t, 365, got.Items[0].MinMonthly, 0.001)
+	// worker: 32*0.01 + 128*0.005 + 100*0.001 = 1.06 per hour
+	assert.InDelta(t, 1.06, got.Items[1].HourlyUnit, 0.001)
+	assert.InDelta(t, 773.8, got.Items[1].MinMonthly, 0.001)
+	assert.InDelta(t, 2321.4, got.Items[1].MaxMonthly, 0.001)
+	// ips: 2*0.01 per hour
+	assert.InDelta(t, 14.6, got.Items[2].MinMonthly, 0.001)
+
+	assert.InDelta(t, 1153.4, got.MinMonthly, 0.001)
+	assert.InDelta(t, 2701, got.MaxMonthly, 0.001)
+
+	_, err = estimateClusterCosts(p, "test", "n1-medium-x86", workers, nil)
+	assert.EqualError(t, err, "firewall: machine type n1-medium-x86 is not contained in the price table costs-machine-types")
+
+	_, err = estimateClusterCosts(p, "test", "s2-xlarge-x86", []*models.V1Worker{{Name: pointer.StringPtr("default")}}, nil)
+	assert.EqualError(t, err, "worker group default: machine type is not set and defaulted by the api, it has to be given to estimate the costs")
+}
+
+func Test_confirmClusterCreate(t *testing.T) {
+	t.Cleanup(viper.Reset)
+	// a malformed price table must neither fail nor block a create which does not run in a terminal
+	viper.Set("costs-machine-types", "c1-xlarge-x86")
+
+	_, err := priceTableFromConfig()
+	require.Error(t, err)
+	assert.NoError(t, confirmClusterCreate(&models.V1ClusterCreateRequest{Name: pointer.StringPtr("test")}))
+}
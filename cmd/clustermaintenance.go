@@ -0,0 +1,119 @@
+package cmd
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/fi-ts/cloud-go/api/models"
+)
+
+const (
+	// maintenanceTimeFormat is the format of the maintenance time window used by the api, e.g. 220000+0100
+	maintenanceTimeFormat = "150405-0700"
+
+	maintenanceBeginDefault = "220000+0100"
+	maintenanceEndDefault   = "233000+0100"
+)
+
+var maintenanceTimeAPIFormat = regexp.MustCompile(`^\d{6}[+-]\d{4}$`)
+
+// parseMaintenanceTime converts a human readable time of day with an optional timezone like "02:00 Europe/Berlin"
+// into the api format. the offset of the timezone is determined for the given day, times already given in the api
+// format are returned as is.
+func parseMaintenanceTime(value string, day time.Time) (string, error) {
+	value = strings.TrimSpace(value)
+	if maintenanceTimeAPIFormat.MatchString(value) {
+		return value, nil
+	}
+
+	parts := strings.Fields(value)
+	if len(parts) == 0 || len(parts) > 2 {
+		return "", fmt.Errorf("maintenance time %q must be in the form <hh:mm> [<timezone>], e.g. \"02:00 Europe/Berlin\"", value)
+	}
+
+	loc := time.Local
+	if len(parts) == 2 {
+		var err error
+		loc, err = time.LoadLocation(parts[1])
+		if err != nil {
+			return "", fmt.Errorf("maintenance time %q contains an unknown timezone: %w", value, err)
+		}
+	}
+
+	var clock time.Time
+	var err error
+	for _, layout := range []string{"15:04", "15:04:05"} {
+		clock, err = time.Parse(layout, parts[0])
+		if err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return "", fmt.Errorf("maintenance time %q must be in the form <hh:mm> [<timezone>], e.g. \"02:00 Europe/Berlin\"", value)
+	}
+
+	day = day.In(loc)
+	t := time.Date(day.Year(), day.Month(), day.Day(), clock.Hour(), clock.Minute(), clock.Second(), 0, loc)
+	return t.Format(maintenanceTimeFormat), nil
+}
+
+// maintenanceTimeWindowFromFlags returns the time window from the given begin and end values,
+// unset values are taken from the given current time window.
+func maintenanceTimeWindowFromFlags(begin, end string, current *models.V1MaintenanceTimeWindow, now time.Time) (*models.V1MaintenanceTimeWindow, error) {
+	tw := &models.V1MaintenanceTimeWindow{}
+	if current != nil {
+		tw.Begin = current.Begin
+		tw.End = current.End
+	}
+	if begin != "" {
+		b, err := parseMaintenanceTime(begin, now)
+		if err != nil {
+			return nil, err
+		}
+		tw.Begin = &b
+	}
+	if end != "" {
+		e, err := parseMaintenanceTime(end, now)
+		if err != nil {
+			return nil, err
+		}
+		tw.End = &e
+	}
+	if tw.Begin == nil || tw.End == nil {
+		return nil, fmt.Errorf("maintenance time window requires begin and end")
+	}
+	return tw, nil
+}
+
+// nextMaintenanceWindow returns the begin and end of the next maintenance window after now,
+// if now is within a maintenance window this window is returned.
+func nextMaintenanceWindow(tw *models.V1MaintenanceTimeWindow, now time.Time) (time.Time, time.Time, error) {
+	if tw == nil || tw.Begin == nil || tw.End == nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("cluster has no maintenance time window")
+	}
+	begin, err := time.Parse(maintenanceTimeFormat, *tw.Begin)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("unable to parse begin of maintenance time window: %w", err)
+	}
+	end, err := time.Parse(maintenanceTimeFormat, *tw.End)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("unable to parse end of maintenance time window: %w", err)
+	}
+
+	day := now.In(begin.Location())
+	nextBegin := time.Date(day.Year(), day.Month(), day.Day(), begin.Hour(), begin.Minute(), begin.Second(), 0, begin.Location())
+	duration := end.Sub(begin)
+	if duration <= 0 {
+		// the window spans midnight
+		duration += 24 * time.Hour
+	}
+
+	// start with the window of the previous day in case it is still active
+	nextBegin = nextBegin.AddDate(0, 0, -1)
+	for !nextBegin.Add(duration).After(now) {
+		nextBegin = nextBegin.AddDate(0, 0, 1)
+	}
+	return nextBegin, nextBegin.Add(duration), nil
+}
@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fi-ts/cloud-go/api/models"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/utils/pointer"
+)
+
+func Test_parseMaintenanceTime(t *testing.T) {
+	winter := time.Date(2021, 1, 15, 12, 0, 0, 0, time.UTC)
+	summer := time.Date(2021, 7, 15, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		value   string
+		day     time.Time
+		want    string
+		wantErr bool
+	}{
+		{
+			name:  "api format",
+			value: "220000+0100",
+			day:   winter,
+			want:  "220000+0100",
+		},
+		{
+			name:  "timezone in winter",
+			value: "02:00 Europe/Berlin",
+			day:   winter,
+			want:  "020000+0100",
+		},
+		{
+			name:  "timezone in summer",
+			value: "02:00 Europe/Berlin",
+			day:   summer,
+			want:  "020000+0200",
+		},
+		{
+			name:  "with seconds",
+			value: "23:30:15 UTC",
+			day:   summer,
+			want:  "233015+0000",
+		},
+		{
+			name:    "unknown timezone",
+			value:   "02:00 Mars/Olympus",
+			day:     winter,
+			wantErr: true,
+		},
+		{
+			name:    "invalid time",
+			value:   "2am",
+			day:     winter,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseMaintenanceTime(tt.value, tt.day)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_nextMaintenanceWindow(t *testing.T) {
+	tz := time.FixedZone("", 3600)
+	tw := &models.V1MaintenanceTimeWindow{
+		Begin: pointer.StringPtr("233000+0100"),
+		End:   pointer.StringPtr("013000+0100"),
+	}
+
+	tests := []struct {
+		name      string
+		now       time.Time
+		wantBegin time.Time
+	}{
+		{
+			name:      "before the window",
+			now:       time.Date(2021, 1, 15, 12, 0, 0, 0, tz),
+			wantBegin: time.Date(2021, 1, 15, 23, 30, 0, 0, tz),
+		},
+		{
+			name:      "within the window after midnight",
+			now:       time.Date(2021, 1, 16, 0, 30, 0, 0, tz),
+			wantBegin: time.Date(2021, 1, 15, 23, 30, 0, 0, tz),
+		},
+		{
+			name:      "after the window",
+			now:       time.Date(2021, 1, 16, 2, 0, 0, 0, tz),
+			wantBegin: time.Date(2021, 1, 16, 23, 30, 0, 0, tz),
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			begin, end, err := nextMaintenanceWindow(tw, tt.now)
+			assert.NoError(t, err)
+			assert.True(t, tt.wantBegin.Equal(begin), "expected begin %s, got %s", tt.wantBegin, begin)
+			assert.Equal(t, 2*time.Hour, end.Sub(begin))
+		})
+	}
+}
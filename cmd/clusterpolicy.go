@@ -0,0 +1,281 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/fi-ts/cloud-go/api/models"
+	"github.com/fi-ts/cloudctl/cmd/helper"
+	"github.com/fi-ts/cloudctl/pkg/api"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+	"k8s.io/utils/pointer"
+)
+
+// clusterPolicies is the content of the policy file which is configured per context
+type clusterPolicies struct {
+	Policies []clusterPolicy `yaml:"policies"`
+}
+
+// clusterPolicy is a rule clusters have to comply with before they are created or updated. violations of hard policies
+// can not be overridden, violations of other policies have to be confirmed.
+type clusterPolicy struct {
+	Name        string `yaml:"name"`
+	Description string `yaml:"description"`
+	Hard        bool   `yaml:"hard"`
+	// Purpose restricts the policy to clusters of the given purpose
+	Purpose string `yaml:"purpose"`
+
+	MinSize               int32    `yaml:"minsize"`
+	ForbidAllowPrivileged bool     `yaml:"forbid-allowprivileged"`
+	ForbidAuditOff        bool     `yaml:"forbid-audit-off"`
+	RequiredLabels        []string `yaml:"required-labels"`
+}
+
+// policyCluster contains the settings of a cluster which are checked by the policies
+type policyCluster struct {
+	Name            string
+	Purpose         string
+	Labels          map[string]string
+	AllowPrivileged bool
+	AuditOff        bool
+	Workers         []*models.V1Worker
+}
+
+// clusterPolicyViolation is a policy a cluster does not comply with
+type clusterPolicyViolation struct {
+	Policy clusterPolicy
+	Reason string
+}
+
+func (v clusterPolicyViolation) String() string {
+	kind := "soft"
+	if v.Policy.Hard {
+		kind = "hard"
+	}
+	s := fmt.Sprintf("policy %s (%s): %s", v.Policy.Name, kind, v.Reason)
+	if v.Policy.Description != "" {
+		s += " (" + v.Policy.Description + ")"
+	}
+	return s
+}
+
+// loadClusterPolicies reads the policy file of the current context, no policies are returned if none is configured.
+func loadClusterPolicies() ([]clusterPolicy, error) {
+	path := api.MustDefaultContext().Policy
+	if path == "" {
+		return nil, nil
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read policy file of context: %w", err)
+	}
+	return parseClusterPolicies(content)
+}
+
+func parseClusterPolicies(content []byte) ([]clusterPolicy, error) {
+	var policies clusterPolicies
+	dec := yaml.NewDecoder(bytes.NewReader(content))
+	dec.KnownFields(true)
+	err := dec.Decode(&policies)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse policy file: %w", err)
+	}
+	for i, p := range policies.Policies {
+		if p.Name == "" {
+			return nil, fmt.Errorf("policy %d of policy file has no name", i+1)
+		}
+	}
+	return policies.Policies, nil
+}
+
+// checkClusterPolicies returns all policies the given cluster does not comply with
+func checkClusterPolicies(policies []clusterPolicy, pc policyCluster) []clusterPolicyViolation {
+	var violations []clusterPolicyViolation
+	for _, p := range policies {
+		if p.Purpose != "" && p.Purpose != pc.Purpose {
+			continue
+		}
+		violate := func(format string, args ...interface{}) {
+			violations = append(violations, clusterPolicyViolation{Policy: p, Reason: fmt.Sprintf(format, args...)})
+		}
+
+		if p.MinSize > 0 {
+			for _, w := range pc.Workers {
+				min := pointer.Int32Deref(w.Minimum, 0)
+				if min < p.MinSize {
+					violate("worker group %s has a minsize of %d, at least %d is required", pointer.StringDeref(w.Name, ""), min, p.MinSize)
+				}
+			}
+		}
+		if p.ForbidAllowPrivileged && pc.AllowPrivileged {
+			violate("privileged containers must not be allowed")
+		}
+		if p.ForbidAuditOff && pc.AuditOff {
+			violate("audit must not be turned off")
+		}
+		for _, l := range p.RequiredLabels {
+			if _, ok := pc.Labels[l]; !ok {
+				violate("label %s is required", l)
+			}
+		}
+	}
+	return violations
+}
+
+// newClusterPolicyViolations returns the policies the cluster violates. on updates the state before the update is given,
+// violations which already existed before are only printed as warning to not block unrelated changes of a cluster.
+func newClusterPolicyViolations(policies []clusterPolicy, pc policyCluster, before *policyCluster) []clusterPolicyViolation {
+	existing := map[string]bool{}
+	if before != nil {
+		for _, v := range checkClusterPolicies(policies, *before) {
+			existing[v.String()] = true
+		}
+	}
+
+	var violations []clusterPolicyViolation
+	var warnings []string
+	for _, v := range checkClusterPolicies(policies, pc) {
+		if existing[v.String()] {
+			warnings = append(warnings, "  "+v.String())
+			continue
+		}
+		violations = append(violations, v)
+	}
+	if len(warnings) > 0 {
+		fmt.Fprintf(os.Stderr, "%s cluster %s already violates policies:\n%s\n", color.YellowString("⚠"), pc.Name, strings.Join(warnings, "\n"))
+	}
+	return violations
+}
+
+// hardClusterPolicyViolations returns an error listing all violations if any of them violates a hard policy
+func hardClusterPolicyViolations(name string, violations []clusterPolicyViolation) error {
+	for _, v := range violations {
+		if v.Policy.Hard {
+			return fmt.Errorf("cluster %s violates policies:\n%s", name, clusterPolicyViolationLines(violations))
+		}
+	}
+	return nil
+}
+
+func clusterPolicyViolationLines(violations []clusterPolicyViolation) string {
+	var lines []string
+	for _, v := range violations {
+		lines = append(lines, "  "+v.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// enforceClusterPolicies checks the cluster against the policies of the current context. violations of hard policies are
+// returned as error, violations of other policies have to be confirmed, which can be skipped with --yes-i-really-mean-it.
+func enforceClusterPolicies(pc policyCluster, before *policyCluster) error {
+	policies, err := loadClusterPolicies()
+	if err != nil {
+		return err
+	}
+
+	violations := newClusterPolicyViolations(policies, pc, before)
+	if len(violations) == 0 {
+		return nil
+	}
+	err = hardClusterPolicyViolations(pc.Name, violations)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "%s cluster %s violates policies:\n%s\n", color.YellowString("⚠"), pc.Name, clusterPolicyViolationLines(violations))
+	if viper.GetBool("yes-i-really-mean-it") {
+		return nil
+	}
+	return helper.Prompt("Are you sure? (y/n)", "y")
+}
+
+// policyClusterFromCreateRequest returns the settings of the cluster to be created
+func policyClusterFromCreateRequest(scr *models.V1ClusterCreateRequest) policyCluster {
+	pc := policyCluster{
+		Name:    pointer.StringDeref(scr.Name, ""),
+		Purpose: pointer.StringDeref(scr.Purpose, ""),
+		Labels:  scr.Labels,
+		Workers: scr.Workers,
+	}
+	if scr.Kubernetes != nil {
+		pc.AllowPrivileged = pointer.BoolDeref(scr.Kubernetes.AllowPrivilegedContainers, false)
+	}
+	if scr.Audit != nil {
+		pc.AuditOff = !pointer.BoolDeref(scr.Audit.ClusterAudit, true)
+	}
+	return pc
+}
+
+// policyClusterFromResponse returns the settings of an existing cluster
+func policyClusterFromResponse(current *models.V1ClusterResponse) policyCluster {
+	pc := policyCluster{
+		Name:     pointer.StringDeref(current.Name, ""),
+		Purpose:  pointer.StringDeref(current.Purpose, ""),
+		Labels:   current.Labels,
+		Workers:  current.Workers,
+		AuditOff: clusterAuditName(current) == "off",
+	}
+	if current.Kubernetes != nil {
+		pc.AllowPrivileged = pointer.BoolDeref(current.Kubernetes.AllowPrivilegedContainers, false)
+	}
+	return pc
+}
+
+// policyClusterFromUpdateRequest returns the settings of the cluster after the update was applied
+func policyClusterFromUpdateRequest(current *models.V1ClusterResponse, cur *models.V1ClusterUpdateRequest) policyCluster {
+	pc := policyClusterFromResponse(current)
+	if cur.Purpose != nil {
+		pc.Purpose = *cur.Purpose
+	}
+	if cur.Labels != nil {
+		pc.Labels = cur.Labels
+	}
+	if cur.Workers != nil {
+		pc.Workers = cur.Workers
+	}
+	if cur.Kubernetes != nil && cur.Kubernetes.AllowPrivilegedContainers != nil {
+		pc.AllowPrivileged = *cur.Kubernetes.AllowPrivilegedContainers
+	}
+	if cur.Audit != nil {
+		pc.AuditOff = !pointer.BoolDeref(cur.Audit.ClusterAudit, true)
+	}
+	return pc
+}
+
+// enforceClusterUpdatePolicies checks the cluster after the update against the policies of the current context
+func enforceClusterUpdatePolicies(current *models.V1ClusterResponse, cur *models.V1ClusterUpdateRequest) error {
+	before := policyClusterFromResponse(current)
+	return enforceClusterPolicies(policyClusterFromUpdateRequest(current, cur), &before)
+}
+
+// checkClustersUpdatePolicies checks the clusters after their updates against the policies of the current context without
+// prompting, so the violations of soft policies can be confirmed for all clusters at once. the new violations are returned
+// by cluster id, an error is returned if any cluster violates a hard policy.
+func checkClustersUpdatePolicies(clusters []*models.V1ClusterResponse, requests map[string]*models.V1ClusterUpdateRequest) (map[string][]clusterPolicyViolation, error) {
+	policies, err := loadClusterPolicies()
+	if err != nil {
+		return nil, err
+	}
+
+	result := map[string][]clusterPolicyViolation{}
+	var errs []string
+	for _, current := range clusters {
+		before := policyClusterFromResponse(current)
+		pc := policyClusterFromUpdateRequest(current, requests[*current.ID])
+		violations := newClusterPolicyViolations(policies, pc, &before)
+		err := hardClusterPolicyViolations(pc.Name, violations)
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		result[*current.ID] = violations
+	}
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("%s", strings.Join(errs, "\n"))
+	}
+	return result, nil
+}
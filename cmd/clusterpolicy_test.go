@@ -0,0 +1,133 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fi-ts/cloud-go/api/models"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/utils/pointer"
+)
+
+const testPolicies = `
+policies:
+  - name: production-size
+    purpose: production
+    minsize: 3
+    hard: true
+  - name: no-privileged
+    forbid-allowprivileged: true
+    hard: true
+  - name: audit
+    forbid-audit-off: true
+  - name: team-label
+    required-labels:
+      - team
+`
+
+func Test_parseClusterPolicies(t *testing.T) {
+	policies, err := parseClusterPolicies([]byte(testPolicies))
+	require.NoError(t, err)
+	require.Len(t, policies, 4)
+	assert.Equal(t, clusterPolicy{Name: "production-size", Purpose: "production", MinSize: 3, Hard: true}, policies[0])
+
+	_, err = parseClusterPolicies([]byte("policies:\n  - name: typo\n    minsizes: 3\n"))
+	assert.Error(t, err)
+
+	_, err = parseClusterPolicies([]byte("policies:\n  - minsize: 3\n"))
+	assert.EqualError(t, err, "policy 1 of policy file has no name")
+}
+
+func Test_checkClusterPolicies(t *testing.T) {
+	policies, err := parseClusterPolicies([]byte(testPolicies))
+	require.NoError(t, err)
+
+	compliant := policyCluster{
+		Name:    "test",
+		Purpose: "production",
+		Labels:  map[string]string{"team": "a"},
+		Workers: []*models.V1Worker{{Name: pointer.StringPtr("default"), Minimum: pointer.Int32Ptr(3)}},
+	}
+	assert.Empty(t, checkClusterPolicies(policies, compliant))
+
+	violating := policyCluster{
+		Name:            "test",
+		Purpose:         "production",
+		AllowPrivileged: true,
+		AuditOff:        true,
+		Workers:         []*models.V1Worker{{Name: pointer.StringPtr("default"), Minimum: pointer.Int32Ptr(1)}},
+	}
+	var got []string
+	for _, v := range checkClusterPolicies(policies, violating) {
+		got = append(got, v.String())
+	}
+	assert.Equal(t, []string{
+		"policy production-size (hard): worker group default has a minsize of 1, at least 3 is required",
+		"policy no-privileged (hard): privileged containers must not be allowed",
+		"policy audit (soft): audit must not be turned off",
+		"policy team-label (soft): label team is required",
+	}, got)
+
+	violating.Purpose = "evaluation"
+	assert.Len(t, checkClusterPolicies(policies, violating), 3, "production-size only applies to production clusters")
+}
+
+func Test_policyClusterFromUpdateRequest(t *testing.T) {
+	current := &models.V1ClusterResponse{
+		Name:                     pointer.StringPtr("test"),
+		Purpose:                  pointer.StringPtr("evaluation"),
+		Labels:                   map[string]string{"team": "a"},
+		Kubernetes:               &models.V1Kubernetes{AllowPrivilegedContainers: pointer.BoolPtr(false)},
+		ControlPlaneFeatureGates: []string{"clusterAudit"},
+	}
+
+	got := policyClusterFromUpdateRequest(current, &models.V1ClusterUpdateRequest{})
+	assert.Equal(t, policyCluster{Name: "test", Purpose: "evaluation", Labels: map[string]string{"team": "a"}}, got)
+
+	got = policyClusterFromUpdateRequest(current, &models.V1ClusterUpdateRequest{
+		Purpose:    pointer.StringPtr("production"),
+		Kubernetes: &models.V1Kubernetes{AllowPrivilegedContainers: pointer.BoolPtr(true)},
+		Audit:      auditConfigOptions["off"].Config,
+	})
+	assert.Equal(t, policyCluster{Name: "test", Purpose: "production", Labels: map[string]string{"team": "a"}, AllowPrivileged: true, AuditOff: true}, got)
+}
+
+// useTestPolicies configures a context with the test policies as policy file for the duration of the test
+func useTestPolicies(t *testing.T) {
+	dir := t.TempDir()
+	policyFile := filepath.Join(dir, "policies.yaml")
+	require.NoError(t, os.WriteFile(policyFile, []byte(testPolicies), 0600))
+	configFile := filepath.Join(dir, "config.yaml")
+	require.NoError(t, os.WriteFile(configFile, []byte(fmt.Sprintf("current: test\ncontexts:\n  test:\n    policy: %s\n", policyFile)), 0600))
+
+	t.Cleanup(viper.Reset)
+	viper.SetConfigFile(configFile)
+}
+
+func Test_checkClustersUpdatePolicies(t *testing.T) {
+	useTestPolicies(t)
+
+	clusters := []*models.V1ClusterResponse{
+		{ID: pointer.StringPtr("c1"), Name: pointer.StringPtr("shop"), Labels: map[string]string{"team": "a"}},
+		{ID: pointer.StringPtr("c2"), Name: pointer.StringPtr("blog"), Labels: map[string]string{"team": "b"}},
+	}
+
+	violations, err := checkClustersUpdatePolicies(clusters, map[string]*models.V1ClusterUpdateRequest{
+		"c1": {Labels: map[string]string{}},
+		"c2": {},
+	})
+	require.NoError(t, err)
+	require.Len(t, violations["c1"], 1)
+	assert.Equal(t, "policy team-label (soft): label team is required", violations["c1"][0].String())
+	assert.Empty(t, violations["c2"])
+
+	_, err = checkClustersUpdatePolicies(clusters, map[string]*models.V1ClusterUpdateRequest{
+		"c1": {Labels: map[string]string{}},
+		"c2": {Kubernetes: &models.V1Kubernetes{AllowPrivilegedContainers: pointer.BoolPtr(true)}},
+	})
+	assert.EqualError(t, err, "cluster blog violates policies:\n  policy no-privileged (hard): privileged containers must not be allowed")
+}
@@ -0,0 +1,231 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/fi-ts/cloud-go/api/client/cluster"
+	"github.com/fi-ts/cloud-go/api/models"
+	"github.com/spf13/viper"
+	"golang.org/x/crypto/ssh"
+)
+
+// portForward is a local port forwarding in the form of ssh -L.
+type portForward struct {
+	LocalAddress  string
+	RemoteAddress string
+}
+
+// parsePortForward parses a forwarding of the form [<bind address>:]<local port>:<remote host>:<remote port>,
+// the local port is bound to localhost if no bind address is given. ipv6 addresses are given in brackets.
+func parsePortForward(spec string) (*portForward, error) {
+	parts, err := splitPortForward(spec)
+	if err != nil {
+		return nil, err
+	}
+	bind := "localhost"
+	switch len(parts) {
+	case 3:
+	case 4:
+		bind, parts = parts[0], parts[1:]
+	default:
+		return nil, fmt.Errorf("port forwarding needs format [<bind address>:]<local port>:<remote host>:<remote port> but got %q", spec)
+	}
+	localPort, remoteHost, remotePort := parts[0], parts[1], parts[2]
+	for _, p := range []string{localPort, remotePort} {
+		port, err := strconv.ParseUint(p, 10, 16)
+		if err != nil || port == 0 {
+			return nil, fmt.Errorf("port forwarding %q contains an invalid port %q", spec, p)
+		}
+	}
+	if remoteHost == "" {
+		return nil, fmt.Errorf("port forwarding %q contains no remote host", spec)
+	}
+	return &portForward{
+		LocalAddress:  net.JoinHostPort(bind, localPort),
+		RemoteAddress: net.JoinHostPort(remoteHost, remotePort),
+	}, nil
+}
+
+// splitPortForward splits the given forwarding at the colons which are not enclosed in brackets and removes the brackets.
+func splitPortForward(spec string) ([]string, error) {
+	var parts []string
+	start, inBrackets := 0, false
+	for i, r := range spec {
+		switch {
+		case r == '[':
+			inBrackets = true
+		case r == ']':
+			inBrackets = false
+		case r == ':' && !inBrackets:
+			parts = append(parts, spec[start:i])
+			start = i + 1
+		}
+	}
+	if inBrackets {
+		return nil, fmt.Errorf("port forwarding %q contains an unclosed bracket", spec)
+	}
+	parts = append(parts, spec[start:])
+	for i, p := range parts {
+		if strings.HasPrefix(p, "[") && strings.HasSuffix(p, "]") {
+			parts[i] = p[1 : len(p)-1]
+		}
+	}
+	return parts, nil
+}
+
+func (c *config) clusterMachinePortForward(args []string) error {
+	cid, err := c.clusterID("port-forward", args)
+	if err != nil {
+		return err
+	}
+	mid := viper.GetString("machineid")
+
+	var forwards []*portForward
+	for _, spec := range viper.GetStringSlice("local") {
+		f, err := parsePortForward(spec)
+		if err != nil {
+			return err
+		}
+		forwards = append(forwards, f)
+	}
+	if len(forwards) == 0 {
+		return fmt.Errorf("at least one port forwarding must be given with -L")
+	}
+
+	findRequest := cluster.NewFindClusterParams()
+	findRequest.SetID(cid)
+	shoot, err := c.cloud.Cluster.FindCluster(findRequest, nil)
+	if err != nil {
+		return err
+	}
+
+	keypair, err := c.sshKeyPair(cid)
+	if err != nil {
+		return err
+	}
+
+	for _, fw := range shoot.Payload.Firewalls {
+		if *fw.ID != mid {
+			continue
+		}
+		ip, err := firewallSSHIP(fw)
+		if err != nil {
+			return err
+		}
+		client, err := sshConnect(net.JoinHostPort(ip, sshPort), firewallSSHUser, keypair.privatekey)
+		if err != nil {
+			return err
+		}
+		defer client.Close()
+
+		// without a private network only hosts in the default vrf of the firewall can be reached
+		tenant, _ := firewallTenantNetwork(fw)
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		return forwardPorts(ctx, client, portForwardDialer(client, tenant), forwards)
+	}
+
+	return fmt.Errorf("firewall:%s not found in cluster:%s", mid, cid)
+}
+
+// portForwardDialer returns a function which opens the connections to the remote addresses through the client. ips in the
+// private network of the cluster, like the ones of the workers, are dialed from the tenant vrf of the firewall as they are
+// not routed in its default vrf. all other addresses are dialed from the default vrf.
+func portForwardDialer(client *ssh.Client, tenant *models.ModelsV1MachineNetwork) func(address string) (net.Conn, error) {
+	return func(address string) (net.Conn, error) {
+		host, _, err := net.SplitHostPort(address)
+		if err != nil {
+			return nil, err
+		}
+		if tenant != nil && tenantNetworkContains(tenant, host) {
+			return dialVRF(client, tenantVRF(tenant), address)
+		}
+		return client.Dial("tcp", address)
+	}
+}
+
+// forwardPorts listens on the local addresses of the given forwards and tunnels every connection opened with dial
+// to the remote address until the context is done or the ssh connection is closed.
+func forwardPorts(ctx context.Context, client *ssh.Client, dial func(address string) (net.Conn, error), forwards []*portForward) error {
+	var listeners []net.Listener
+	defer func() {
+		for _, l := range listeners {
+			_ = l.Close()
+		}
+	}()
+	for _, f := range forwards {
+		l, err := net.Listen("tcp", f.LocalAddress)
+		if err != nil {
+			return fmt.Errorf("unable to listen on %s: %w", f.LocalAddress, err)
+		}
+		listeners = append(listeners, l)
+	}
+
+	var wg sync.WaitGroup
+	for i, l := range listeners {
+		f := forwards[i]
+		l := l
+		fmt.Printf("forwarding %s -> %s\n", l.Addr(), f.RemoteAddress)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				local, err := l.Accept()
+				if err != nil {
+					return
+				}
+				go tunnel(dial, local, f.RemoteAddress)
+			}
+		}()
+	}
+
+	closed := make(chan error, 1)
+	go func() {
+		closed <- client.Wait()
+	}()
+
+	var err error
+	select {
+	case <-ctx.Done():
+	case err = <-closed:
+		if err == nil {
+			err = fmt.Errorf("ssh connection closed")
+		}
+	}
+	for _, l := range listeners {
+		_ = l.Close()
+	}
+	wg.Wait()
+	return err
+}
+
+// tunnel copies data between the local connection and a connection to the remote address opened with dial.
+func tunnel(dial func(address string) (net.Conn, error), local net.Conn, remoteAddress string) {
+	defer local.Close()
+	remote, err := dial(remoteAddress)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "unable to connect to %s: %v\n", remoteAddress, err)
+		return
+	}
+	defer remote.Close()
+
+	done := make(chan struct{}, 2)
+	go func() {
+		_, _ = io.Copy(remote, local)
+		done <- struct{}{}
+	}()
+	go func() {
+		_, _ = io.Copy(local, remote)
+		done <- struct{}{}
+	}()
+	<-done
+}
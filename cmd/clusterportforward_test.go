@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_parsePortForward(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    *portForward
+		wantErr bool
+	}{
+		{
+			spec: "9100:10.0.0.5:9100",
+			want: &portForward{LocalAddress: "localhost:9100", RemoteAddress: "10.0.0.5:9100"},
+		},
+		{
+			spec: "0.0.0.0:10250:node-1:10250",
+			want: &portForward{LocalAddress: "0.0.0.0:10250", RemoteAddress: "node-1:10250"},
+		},
+		{
+			spec: "9100:[2001:db8::5]:9100",
+			want: &portForward{LocalAddress: "localhost:9100", RemoteAddress: "[2001:db8::5]:9100"},
+		},
+		{
+			spec: "[::1]:9100:[2001:db8::5]:9100",
+			want: &portForward{LocalAddress: "[::1]:9100", RemoteAddress: "[2001:db8::5]:9100"},
+		},
+		{
+			spec:    "9100:[2001:db8::5:9100",
+			wantErr: true,
+		},
+		{
+			spec:    "9100:10.0.0.5",
+			wantErr: true,
+		},
+		{
+			spec:    "abc:10.0.0.5:9100",
+			wantErr: true,
+		},
+		{
+			spec:    "9100::9100",
+			wantErr: true,
+		},
+		{
+			spec:    "9100:10.0.0.5:70000",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.spec, func(t *testing.T) {
+			got, err := parsePortForward(tt.spec)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,348 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fi-ts/cloud-go/api/models"
+	"github.com/spf13/viper"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+	"golang.org/x/term"
+)
+
+const (
+	sshPort        = "22"
+	sshDialTimeout = 10 * time.Second
+	// bmcConsolePort is the port of the ssh server on the console host which proxies to the serial console of a machine
+	bmcConsolePort = "5222"
+
+	// firewallSSHUser is the user to access firewalls with the ssh keypair of the cluster
+	firewallSSHUser = "metal"
+	// workerSSHUser is the user gardener provisions the ssh keypair of the cluster for on worker nodes
+	workerSSHUser = "gardener"
+)
+
+// firewallSSHIP returns the first ip of the given firewall in a public network with an open ssh port.
+func firewallSSHIP(fw *models.ModelsV1MachineResponse) (string, error) {
+	if fw.Allocation == nil {
+		return "", fmt.Errorf("firewall:%s is not allocated", *fw.ID)
+	}
+	for _, nw := range fw.Allocation.Networks {
+		if *nw.Underlay || *nw.Private {
+			continue
+		}
+		for _, ip := range nw.Ips {
+			if portOpen(ip, sshPort, time.Second) {
+				return ip, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("no ip with a open ssh port found")
+}
+
+// machinePrivateIP returns the ip of the given machine in the private network of the cluster.
+func machinePrivateIP(m *models.ModelsV1MachineResponse) (string, error) {
+	if m.Allocation == nil {
+		return "", fmt.Errorf("machine:%s is not allocated", *m.ID)
+	}
+	for _, nw := range m.Allocation.Networks {
+		if !*nw.Private || *nw.Underlay {
+			continue
+		}
+		if len(nw.Ips) > 0 {
+			return nw.Ips[0], nil
+		}
+	}
+	return "", fmt.Errorf("machine:%s has no ip in a private network", *m.ID)
+}
+
+// firewallTenantNetwork returns the private network of the cluster the given firewall is attached to.
+func firewallTenantNetwork(fw *models.ModelsV1MachineResponse) (*models.ModelsV1MachineNetwork, error) {
+	if fw.Allocation == nil {
+		return nil, fmt.Errorf("firewall:%s is not allocated", *fw.ID)
+	}
+	for _, nw := range fw.Allocation.Networks {
+		if *nw.Private && !*nw.Underlay && nw.Vrf != nil {
+			return nw, nil
+		}
+	}
+	return nil, fmt.Errorf("firewall:%s is not attached to a private network", *fw.ID)
+}
+
+// tenantVRF returns the name of the vrf the given private network is routed in on a firewall.
+func tenantVRF(nw *models.ModelsV1MachineNetwork) string {
+	return fmt.Sprintf("vrf%d", *nw.Vrf)
+}
+
+// tenantNetworkContains returns true if the given host is an ip within the prefixes of the given private network.
+func tenantNetworkContains(nw *models.ModelsV1MachineNetwork, host string) bool {
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	for _, prefix := range nw.Prefixes {
+		_, cidr, err := net.ParseCIDR(prefix)
+		if err == nil && cidr.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// firewallTenantVRFClient connects to the first firewall of the given cluster which is reachable via ssh and returns it
+// together with the vrf of the private network of the cluster on this firewall.
+func firewallTenantVRFClient(shoot *models.V1ClusterResponse, privateKey []byte) (*ssh.Client, string, error) {
+	for _, fw := range shoot.Firewalls {
+		nw, err := firewallTenantNetwork(fw)
+		if err != nil {
+			continue
+		}
+		ip, err := firewallSSHIP(fw)
+		if err != nil {
+			continue
+		}
+		client, err := sshConnect(net.JoinHostPort(ip, sshPort), firewallSSHUser, privateKey)
+		if err != nil {
+			return nil, "", err
+		}
+		return client, tenantVRF(nw), nil
+	}
+	return nil, "", fmt.Errorf("no firewall of cluster:%s reachable via ssh", *shoot.ID)
+}
+
+// vrfDialCommand returns the command which connects its stdin and stdout to the given host and port from within the
+// given vrf. the private network of a cluster is only routed in the tenant vrf of the firewall, not in its default vrf
+// where the ssh server runs, so a plain tcp forwarding of the ssh server can not reach the workers.
+func vrfDialCommand(vrf, host, port string) string {
+	return fmt.Sprintf("sudo -n ip vrf exec %s nc %s %s", shellQuote(vrf), shellQuote(host), shellQuote(port))
+}
+
+// shellQuote quotes the given string for a posix shell.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// sshVRFConn is a connection which was opened by the vrfDialCommand running in a session of an ssh client.
+type sshVRFConn struct {
+	session *ssh.Session
+	stdin   io.WriteCloser
+	stdout  io.Reader
+	remote  string
+}
+
+type sshVRFAddr string
+
+func (a sshVRFAddr) Network() string { return "tcp" }
+func (a sshVRFAddr) String() string  { return string(a) }
+
+func (c *sshVRFConn) Read(b []byte) (int, error)  { return c.stdout.Read(b) }
+func (c *sshVRFConn) Write(b []byte) (int, error) { return c.stdin.Write(b) }
+func (c *sshVRFConn) Close() error {
+	_ = c.stdin.Close()
+	return c.session.Close()
+}
+func (c *sshVRFConn) LocalAddr() net.Addr                { return sshVRFAddr("") }
+func (c *sshVRFConn) RemoteAddr() net.Addr               { return sshVRFAddr(c.remote) }
+func (c *sshVRFConn) SetDeadline(t time.Time) error      { return nil }
+func (c *sshVRFConn) SetReadDeadline(t time.Time) error  { return nil }
+func (c *sshVRFConn) SetWriteDeadline(t time.Time) error { return nil }
+
+// dialVRF opens a connection to the given address from within the given vrf of the host the client is connected to.
+func dialVRF(client *ssh.Client, vrf, address string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+	session, err := client.NewSession()
+	if err != nil {
+		return nil, err
+	}
+	stdin, err := session.StdinPipe()
+	if err != nil {
+		_ = session.Close()
+		return nil, err
+	}
+	stdout, err := session.StdoutPipe()
+	if err != nil {
+		_ = session.Close()
+		return nil, err
+	}
+	session.Stderr = os.Stderr
+	err = session.Start(vrfDialCommand(vrf, host, port))
+	if err != nil {
+		_ = session.Close()
+		return nil, fmt.Errorf("unable to connect to %s in %s: %w", address, vrf, err)
+	}
+	return &sshVRFConn{session: session, stdin: stdin, stdout: stdout, remote: address}, nil
+}
+
+// sshConnectVRF opens an ssh connection to the given address in the given vrf of the host the client is connected to,
+// authenticating with the private key of the cluster.
+func sshConnectVRF(via *ssh.Client, vrf, address, user string, privateKey []byte) (*ssh.Client, error) {
+	config, err := sshClientConfig(user, privateKey)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := dialVRF(via, vrf, address)
+	if err != nil {
+		return nil, err
+	}
+	c, chans, reqs, err := ssh.NewClientConn(conn, address, config)
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	return ssh.NewClient(c, chans, reqs), nil
+}
+
+// sshConnect opens an ssh connection to the given address authenticating with the private key of the cluster.
+func sshConnect(address, user string, privateKey []byte) (*ssh.Client, error) {
+	config, err := sshClientConfig(user, privateKey)
+	if err != nil {
+		return nil, err
+	}
+	return ssh.Dial("tcp", address, config)
+}
+
+func sshClientConfig(user string, privateKey []byte) (*ssh.ClientConfig, error) {
+	signer, err := ssh.ParsePrivateKey(privateKey)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse private key: %w", err)
+	}
+	hostKeyCallback, err := sshHostKeyCallback(viper.GetString("known-hosts"), viper.GetBool("trust-on-first-use"))
+	if err != nil {
+		return nil, err
+	}
+	return &ssh.ClientConfig{
+		User:            user,
+		Auth:            []ssh.AuthMethod{ssh.PublicKeys(signer)},
+		HostKeyCallback: hostKeyCallback,
+		Timeout:         sshDialTimeout,
+	}, nil
+}
+
+// sshHostKeyCallback verifies host keys against the given known_hosts file, unknown hosts are added to the file
+// if trustOnFirstUse is set, otherwise the connection is refused. a changed host key is always refused.
+func sshHostKeyCallback(knownHostsFile string, trustOnFirstUse bool) (ssh.HostKeyCallback, error) {
+	if knownHostsFile == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, fmt.Errorf("unable determine home directory:%w", err)
+		}
+		knownHostsFile = filepath.Join(home, ".ssh", "known_hosts")
+	}
+	err := os.MkdirAll(filepath.Dir(knownHostsFile), 0700)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(knownHostsFile, os.O_CREATE|os.O_RDONLY, 0600)
+	if err != nil {
+		return nil, err
+	}
+	_ = f.Close()
+
+	callback, err := knownhosts.New(knownHostsFile)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read known hosts from %s: %w", knownHostsFile, err)
+	}
+
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		err := callback(hostname, remote, key)
+		var keyErr *knownhosts.KeyError
+		if !errors.As(err, &keyErr) {
+			return err
+		}
+		fingerprint := ssh.FingerprintSHA256(key)
+		if len(keyErr.Want) > 0 {
+			return fmt.Errorf("host key of %s has changed to %s, someone could be eavesdropping on you! remove the old key from %s:%d if the change is expected", hostname, fingerprint, keyErr.Want[0].Filename, keyErr.Want[0].Line)
+		}
+		if !trustOnFirstUse {
+			return fmt.Errorf("host key of %s is unknown, verify the fingerprint %s and connect again with --trust-on-first-use to add it to %s", hostname, fingerprint, knownHostsFile)
+		}
+
+		f, err := os.OpenFile(knownHostsFile, os.O_APPEND|os.O_WRONLY, 0600)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = fmt.Fprintln(f, knownhosts.Line([]string{knownhosts.Normalize(hostname)}, key))
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "permanently added %s with fingerprint %s to %s\n", hostname, fingerprint, knownHostsFile)
+		return nil
+	}, nil
+}
+
+// sshShell opens an interactive shell on the given client with the given environment variables set.
+// if stdin is a terminal, it is put into raw mode and size changes are forwarded to the remote.
+func sshShell(client *ssh.Client, env map[string]string) error {
+	session, err := client.NewSession()
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+
+	for k, v := range env {
+		err = session.Setenv(k, v)
+		if err != nil {
+			return fmt.Errorf("unable to set environment variable %s, the server does not accept it: %w", k, err)
+		}
+	}
+
+	session.Stdin = os.Stdin
+	session.Stdout = os.Stdout
+	session.Stderr = os.Stderr
+
+	fd := int(os.Stdin.Fd())
+	if term.IsTerminal(fd) {
+		state, err := term.MakeRaw(fd)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			_ = term.Restore(fd, state)
+		}()
+
+		width, height, err := term.GetSize(fd)
+		if err != nil {
+			return err
+		}
+		termType := os.Getenv("TERM")
+		if termType == "" {
+			termType = "xterm-256color"
+		}
+		modes := ssh.TerminalModes{
+			ssh.ECHO:          1,
+			ssh.TTY_OP_ISPEED: 14400,
+			ssh.TTY_OP_OSPEED: 14400,
+		}
+		err = session.RequestPty(termType, height, width, modes)
+		if err != nil {
+			return err
+		}
+
+		stop := watchTerminalSize(fd, func(width, height int) {
+			_ = session.WindowChange(height, width)
+		})
+		defer stop()
+	}
+
+	err = session.Shell()
+	if err != nil {
+		return err
+	}
+	err = session.Wait()
+	var exitErr *ssh.ExitError
+	if errors.As(err, &exitErr) {
+		return &exitCodeError{code: exitErr.ExitStatus(), err: err}
+	}
+	return err
+}
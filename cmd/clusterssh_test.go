@@ -0,0 +1,143 @@
+package cmd
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/binary"
+	"io"
+	"net"
+	"path/filepath"
+	"testing"
+
+	"github.com/fi-ts/cloud-go/api/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+	"k8s.io/utils/pointer"
+)
+
+func Test_sshHostKeyCallback(t *testing.T) {
+	newKey := func() ssh.PublicKey {
+		pub, _, err := ed25519.GenerateKey(rand.Reader)
+		require.NoError(t, err)
+		key, err := ssh.NewPublicKey(pub)
+		require.NoError(t, err)
+		return key
+	}
+	knownHosts := filepath.Join(t.TempDir(), "known_hosts")
+	remote := &net.TCPAddr{IP: net.ParseIP("1.2.3.4"), Port: 22}
+	key := newKey()
+
+	strict, err := sshHostKeyCallback(knownHosts, false)
+	require.NoError(t, err)
+	assert.Error(t, strict("1.2.3.4:22", remote, key), "unknown host must be refused")
+
+	tofu, err := sshHostKeyCallback(knownHosts, true)
+	require.NoError(t, err)
+	assert.NoError(t, tofu("1.2.3.4:22", remote, key), "unknown host must be trusted on first use")
+
+	// the known hosts file is read when creating the callback
+	strict, err = sshHostKeyCallback(knownHosts, false)
+	require.NoError(t, err)
+	assert.NoError(t, strict("1.2.3.4:22", remote, key), "known host must be accepted")
+
+	tofu, err = sshHostKeyCallback(knownHosts, true)
+	require.NoError(t, err)
+	assert.Error(t, tofu("1.2.3.4:22", remote, newKey()), "changed host key must be refused even on first use")
+}
+
+func Test_firewallTenantNetwork(t *testing.T) {
+	internet := &models.ModelsV1MachineNetwork{Private: pointer.BoolPtr(false), Underlay: pointer.BoolPtr(false), Vrf: pointer.Int64Ptr(104009), Ips: []string{"212.34.1.2"}}
+	underlay := &models.ModelsV1MachineNetwork{Private: pointer.BoolPtr(false), Underlay: pointer.BoolPtr(true), Vrf: pointer.Int64Ptr(0), Ips: []string{"10.1.0.1"}}
+	tenant := &models.ModelsV1MachineNetwork{Private: pointer.BoolPtr(true), Underlay: pointer.BoolPtr(false), Vrf: pointer.Int64Ptr(3981), Ips: []string{"10.0.0.1"}, Prefixes: []string{"10.0.0.0/22"}}
+	fw := &models.ModelsV1MachineResponse{
+		ID:         pointer.StringPtr("fw-1"),
+		Allocation: &models.ModelsV1MachineAllocation{Networks: []*models.ModelsV1MachineNetwork{internet, underlay, tenant}},
+	}
+
+	got, err := firewallTenantNetwork(fw)
+	require.NoError(t, err)
+	assert.Equal(t, tenant, got)
+	assert.Equal(t, "vrf3981", tenantVRF(got))
+	assert.True(t, tenantNetworkContains(got, "10.0.3.5"))
+	assert.False(t, tenantNetworkContains(got, "10.0.4.5"))
+	assert.False(t, tenantNetworkContains(got, "worker-1"))
+
+	ip, err := machinePrivateIP(fw)
+	require.NoError(t, err)
+	assert.Equal(t, "10.0.0.1", ip)
+
+	_, err = firewallTenantNetwork(&models.ModelsV1MachineResponse{ID: pointer.StringPtr("fw-2"), Allocation: &models.ModelsV1MachineAllocation{Networks: []*models.ModelsV1MachineNetwork{internet}}})
+	assert.EqualError(t, err, "firewall:fw-2 is not attached to a private network")
+}
+
+func Test_vrfDialCommand(t *testing.T) {
+	assert.Equal(t, `sudo -n ip vrf exec 'vrf3981' nc '10.0.0.5' '22'`, vrfDialCommand("vrf3981", "10.0.0.5", "22"))
+	assert.Equal(t, `sudo -n ip vrf exec 'vrf3981' nc 'a'\''; reboot' '22'`, vrfDialCommand("vrf3981", "a'; reboot", "22"))
+}
+
+// Test_dialVRF runs dialVRF against an ssh server which echoes the data of exec sessions back.
+func Test_dialVRF(t *testing.T) {
+	_, hostKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	signer, err := ssh.NewSignerFromKey(hostKey)
+	require.NoError(t, err)
+	serverConfig := &ssh.ServerConfig{NoClientAuth: true}
+	serverConfig.AddHostKey(signer)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+
+	commands := make(chan string, 1)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		_, chans, reqs, err := ssh.NewServerConn(conn, serverConfig)
+		if err != nil {
+			return
+		}
+		go ssh.DiscardRequests(reqs)
+		for newChannel := range chans {
+			channel, requests, err := newChannel.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				for req := range requests {
+					if req.Type != "exec" {
+						_ = req.Reply(false, nil)
+						continue
+					}
+					length := binary.BigEndian.Uint32(req.Payload)
+					commands <- string(req.Payload[4 : 4+length])
+					_ = req.Reply(true, nil)
+					go func() {
+						_, _ = io.Copy(channel, channel)
+						_, _ = channel.SendRequest("exit-status", false, ssh.Marshal(struct{ Status uint32 }{0}))
+						_ = channel.Close()
+					}()
+				}
+			}()
+		}
+	}()
+
+	client, err := ssh.Dial("tcp", l.Addr().String(), &ssh.ClientConfig{User: firewallSSHUser, HostKeyCallback: ssh.InsecureIgnoreHostKey()})
+	require.NoError(t, err)
+	defer client.Close()
+
+	conn, err := dialVRF(client, "vrf3981", "10.0.0.5:22")
+	require.NoError(t, err)
+	defer conn.Close()
+	assert.Equal(t, `sudo -n ip vrf exec 'vrf3981' nc '10.0.0.5' '22'`, <-commands)
+	assert.Equal(t, "10.0.0.5:22", conn.RemoteAddr().String())
+
+	_, err = conn.Write([]byte("ping"))
+	require.NoError(t, err)
+	buf := make([]byte, 4)
+	_, err = io.ReadFull(conn, buf)
+	require.NoError(t, err)
+	assert.Equal(t, "ping", string(buf))
+}
@@ -0,0 +1,36 @@
+//go:build !windows
+// +build !windows
+
+package cmd
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+
+	"golang.org/x/term"
+)
+
+// watchTerminalSize calls onResize whenever the size of the terminal changes until the returned function is called.
+func watchTerminalSize(fd int, onResize func(width, height int)) func() {
+	sigs := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(sigs, syscall.SIGWINCH)
+	go func() {
+		for {
+			select {
+			case <-sigs:
+				width, height, err := term.GetSize(fd)
+				if err == nil {
+					onResize(width, height)
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() {
+		signal.Stop(sigs)
+		close(done)
+	}
+}
@@ -0,0 +1,36 @@
+//go:build windows
+// +build windows
+
+package cmd
+
+import (
+	"time"
+
+	"golang.org/x/term"
+)
+
+// watchTerminalSize calls onResize whenever the size of the terminal changes until the returned function is called.
+// windows has no signal for size changes, so the size is polled.
+func watchTerminalSize(fd int, onResize func(width, height int)) func() {
+	done := make(chan struct{})
+	go func() {
+		width, height, _ := term.GetSize(fd)
+		ticker := time.NewTicker(250 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				w, h, err := term.GetSize(fd)
+				if err == nil && (w != width || h != height) {
+					width, height = w, h
+					onResize(width, height)
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() {
+		close(done)
+	}
+}
@@ -0,0 +1,184 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/fi-ts/cloud-go/api/client/cluster"
+	"github.com/fi-ts/cloud-go/api/models"
+	"github.com/spf13/viper"
+	"k8s.io/utils/pointer"
+)
+
+// sshConfigHost is a host entry of a generated ssh config.
+type sshConfigHost struct {
+	Alias    string
+	HostName string
+	User     string
+	// ProxyCommand connects to the host through a firewall, it is empty for hosts which are reachable directly
+	ProxyCommand string
+}
+
+func (c *config) clusterSSHConfig(args []string) error {
+	cid, err := c.clusterID("ssh-config", args)
+	if err != nil {
+		return err
+	}
+
+	findRequest := cluster.NewFindClusterParams()
+	findRequest.SetID(cid)
+	shoot, err := c.cloud.Cluster.FindCluster(findRequest, nil)
+	if err != nil {
+		return err
+	}
+
+	keypair, err := c.sshKeyPair(cid)
+	if err != nil {
+		return err
+	}
+
+	prefix := viper.GetString("host-prefix")
+	if prefix == "" {
+		prefix = *shoot.Payload.Name
+	}
+
+	dir := viper.GetString("dir")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return fmt.Errorf("unable determine home directory:%w", err)
+		}
+		dir = filepath.Join(home, "."+c.name, "ssh")
+	}
+	dir = filepath.Join(dir, cid)
+
+	hosts := sshConfigHosts(prefix, shoot.Payload)
+	if len(hosts) == 0 {
+		return fmt.Errorf("no firewall of cluster:%s reachable via ssh", cid)
+	}
+	identityFile := filepath.Join(dir, "id_rsa")
+	sshConfig := renderSSHConfig(hosts, identityFile)
+
+	// the private key is only written when there is a config which uses it
+	err = os.MkdirAll(dir, 0700)
+	if err != nil {
+		return err
+	}
+	err = os.WriteFile(identityFile, keypair.privatekey, 0600)
+	if err != nil {
+		return fmt.Errorf("unable to write private key:%s error:%w", identityFile, err)
+	}
+	// WriteFile does not change the permissions of an existing file
+	err = os.Chmod(identityFile, 0600)
+	if err != nil {
+		return err
+	}
+
+	configFile := filepath.Join(dir, "config")
+	existing, err := os.ReadFile(configFile)
+	if err == nil && bytes.Equal(existing, sshConfig) {
+		fmt.Printf("%s ssh config %s is up to date\n", color.GreenString("✔"), configFile)
+		return nil
+	}
+	err = os.WriteFile(configFile, sshConfig, 0600)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%s wrote ssh config for cluster:%s to %s\n", color.GreenString("✔"), cid, configFile)
+	for _, h := range hosts {
+		fmt.Printf("  %s\t%s\n", h.Alias, h.HostName)
+	}
+	fmt.Printf("\nadd the following line to the top of ~/.ssh/config to use the hosts with ssh, run this command again when the machines of the cluster changed:\n\nInclude %s\n", filepath.Join(filepath.Dir(dir), "*", "config"))
+	return nil
+}
+
+// sshConfigHosts returns the host entries for every firewall of the cluster reachable via ssh and for every worker.
+// workers are only reachable from the tenant vrf of a firewall, which a ProxyJump does not use, so their connection
+// is opened by a ProxyCommand which runs nc in this vrf on the first reachable firewall.
+func sshConfigHosts(prefix string, shoot *models.V1ClusterResponse) []sshConfigHost {
+	var hosts []sshConfigHost
+	proxyCommand := ""
+	for _, fw := range sortedMachines(shoot.Firewalls) {
+		ip, err := firewallSSHIP(fw)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "skipping firewall:%s, %v\n", *fw.ID, err)
+			continue
+		}
+		alias := sshHostAlias(prefix, "fw", fw, shoot.Firewalls)
+		hosts = append(hosts, sshConfigHost{
+			Alias:    alias,
+			HostName: ip,
+			User:     firewallSSHUser,
+		})
+		if nw, err := firewallTenantNetwork(fw); err == nil && proxyCommand == "" {
+			proxyCommand = "ssh -q " + alias + " " + vrfDialCommand(tenantVRF(nw), "%h", "%p")
+		}
+	}
+	if proxyCommand == "" {
+		return hosts
+	}
+
+	for _, m := range sortedMachines(shoot.Machines) {
+		ip, err := machinePrivateIP(m)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "skipping worker:%s, %v\n", *m.ID, err)
+			continue
+		}
+		hosts = append(hosts, sshConfigHost{
+			Alias:        sshHostAlias(prefix, "worker", m, shoot.Machines),
+			HostName:     ip,
+			User:         workerSSHUser,
+			ProxyCommand: proxyCommand,
+		})
+	}
+	return hosts
+}
+
+// sshHostAlias returns the host alias of the given machine, which is derived from the last part of its id. the alias
+// does not change when other machines are added or removed, the full id is used if the last part is not unique.
+func sshHostAlias(prefix, kind string, m *models.ModelsV1MachineResponse, all []*models.ModelsV1MachineResponse) string {
+	suffix := func(id string) string {
+		parts := strings.Split(id, "-")
+		return parts[len(parts)-1]
+	}
+	id := pointer.StringDeref(m.ID, "")
+	for _, other := range all {
+		otherID := pointer.StringDeref(other.ID, "")
+		if otherID != id && suffix(otherID) == suffix(id) {
+			return fmt.Sprintf("%s-%s-%s", prefix, kind, id)
+		}
+	}
+	return fmt.Sprintf("%s-%s-%s", prefix, kind, suffix(id))
+}
+
+// sortedMachines returns the given machines ordered by id, so they are processed in a stable order between runs.
+func sortedMachines(ms []*models.ModelsV1MachineResponse) []*models.ModelsV1MachineResponse {
+	sorted := append([]*models.ModelsV1MachineResponse{}, ms...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return *sorted[i].ID < *sorted[j].ID
+	})
+	return sorted
+}
+
+// renderSSHConfig renders the given hosts as OpenSSH client config.
+func renderSSHConfig(hosts []sshConfigHost, identityFile string) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("# generated by cloudctl cluster ssh-config, do not edit\n")
+	for _, h := range hosts {
+		fmt.Fprintf(&buf, "\nHost %s\n", h.Alias)
+		fmt.Fprintf(&buf, "  HostName %s\n", h.HostName)
+		fmt.Fprintf(&buf, "  User %s\n", h.User)
+		if h.ProxyCommand != "" {
+			fmt.Fprintf(&buf, "  ProxyCommand %s\n", h.ProxyCommand)
+		}
+		fmt.Fprintf(&buf, "  IdentityFile %q\n", identityFile)
+		buf.WriteString("  IdentitiesOnly yes\n")
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/fi-ts/cloud-go/api/models"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/utils/pointer"
+)
+
+func Test_renderSSHConfig(t *testing.T) {
+	hosts := []sshConfigHost{
+		{Alias: "prod-fw-ac1f6b7b7f5e", HostName: "212.34.1.2", User: firewallSSHUser},
+		{Alias: "prod-fw-ac1f6b7b7f60", HostName: "212.34.1.3", User: firewallSSHUser},
+		{Alias: "prod-worker-ac1f6b7b7f70", HostName: "10.0.0.5", User: workerSSHUser, ProxyCommand: "ssh -q prod-fw-ac1f6b7b7f5e " + vrfDialCommand("vrf3981", "%h", "%p")},
+	}
+
+	want := `# generated by cloudctl cluster ssh-config, do not edit
+
+Host prod-fw-ac1f6b7b7f5e
+  HostName 212.34.1.2
+  User metal
+  IdentityFile "/home/user/.cloudctl/ssh/c1/id_rsa"
+  IdentitiesOnly yes
+
+Host prod-fw-ac1f6b7b7f60
+  HostName 212.34.1.3
+  User metal
+  IdentityFile "/home/user/.cloudctl/ssh/c1/id_rsa"
+  IdentitiesOnly yes
+
+Host prod-worker-ac1f6b7b7f70
+  HostName 10.0.0.5
+  User gardener
+  ProxyCommand ssh -q prod-fw-ac1f6b7b7f5e sudo -n ip vrf exec 'vrf3981' nc '%h' '%p'
+  IdentityFile "/home/user/.cloudctl/ssh/c1/id_rsa"
+  IdentitiesOnly yes
+`
+
+	assert.Equal(t, want, string(renderSSHConfig(hosts, "/home/user/.cloudctl/ssh/c1/id_rsa")))
+}
+
+func Test_sshHostAlias(t *testing.T) {
+	fw1 := &models.ModelsV1MachineResponse{ID: pointer.StringPtr("00000000-0000-0000-0000-ac1f6b7b7f5e")}
+	fw2 := &models.ModelsV1MachineResponse{ID: pointer.StringPtr("00000000-0000-0000-0000-ac1f6b7b7f60")}
+	fw3 := &models.ModelsV1MachineResponse{ID: pointer.StringPtr("00000000-0000-0000-0001-ac1f6b7b7f60")}
+
+	assert.Equal(t, "prod-fw-ac1f6b7b7f5e", sshHostAlias("prod", "fw", fw1, []*models.ModelsV1MachineResponse{fw1}))
+	assert.Equal(t, "prod-fw-ac1f6b7b7f5e", sshHostAlias("prod", "fw", fw1, []*models.ModelsV1MachineResponse{fw2, fw1}), "alias must not depend on other machines")
+	assert.Equal(t, "prod-fw-00000000-0000-0000-0000-ac1f6b7b7f60", sshHostAlias("prod", "fw", fw2, []*models.ModelsV1MachineResponse{fw1, fw2, fw3}), "full id is used if the last part is not unique")
+
+	worker := &models.ModelsV1MachineResponse{ID: pointer.StringPtr("00000000-0000-0000-0000-ac1f6b7b7f70")}
+	assert.Equal(t, "prod-worker-ac1f6b7b7f70", sshHostAlias("prod", "worker", worker, []*models.ModelsV1MachineResponse{worker}))
+}
@@ -0,0 +1,294 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/fatih/color"
+	"github.com/fi-ts/cloud-go/api/client/cluster"
+	"github.com/fi-ts/cloud-go/api/models"
+	"github.com/fi-ts/cloudctl/cmd/helper"
+	"github.com/fi-ts/cloudctl/cmd/output"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"k8s.io/utils/pointer"
+)
+
+func newClusterUpgradeCmd(c *config) *cobra.Command {
+	upgradeCmd := &cobra.Command{
+		Use:   "upgrade",
+		Short: "plan and apply kubernetes upgrades of a cluster",
+	}
+	upgradePlanCmd := &cobra.Command{
+		Use:   "plan <clusterid>",
+		Short: "show the kubernetes versions the cluster can be upgraded to",
+		Long:  "shows the current kubernetes version of the cluster and its expiration, the latest patch version of the current minor and the minor versions the cluster can be upgraded to one after the other.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return c.clusterUpgradePlan(args)
+		},
+		ValidArgsFunction: c.comp.ClusterListCompletion,
+		PreRun:            bindPFlags,
+	}
+	upgradeApplyCmd := &cobra.Command{
+		Use:   "apply <clusterid>",
+		Short: "upgrade the kubernetes version of the cluster minor by minor",
+		Long:  "upgrades the kubernetes version of the cluster to the given version. kubernetes can not skip minor versions, so the cluster is upgraded to the latest patch of every minor in between, waiting for the success of each step before the next one is started.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return c.clusterUpgradeApply(args)
+		},
+		ValidArgsFunction: c.comp.ClusterListCompletion,
+		PreRun:            bindPFlags,
+	}
+
+	upgradeApplyCmd.Flags().String("version", "", "kubernetes version to upgrade to, defaults to the latest available version.")
+	upgradeApplyCmd.Flags().Duration("timeout", clusterWaitTimeoutDefault, "maximum time to wait for each upgrade step.")
+	must(upgradeApplyCmd.RegisterFlagCompletionFunc("version", c.comp.VersionListCompletion))
+
+	upgradeCmd.AddCommand(upgradePlanCmd)
+	upgradeCmd.AddCommand(upgradeApplyCmd)
+
+	return upgradeCmd
+}
+
+func (c *config) clusterUpgradePlan(args []string) error {
+	ci, err := c.clusterID("upgrade plan", args)
+	if err != nil {
+		return err
+	}
+
+	current, constraints, err := c.clusterUpgradeInputs(ci)
+	if err != nil {
+		return err
+	}
+
+	version := clusterKubernetesVersion(current)
+	targets, err := kubernetesUpgradeTargets(version, constraints.KubernetesVersions)
+	if err != nil {
+		return err
+	}
+
+	plan := &output.ClusterUpgradePlan{
+		ClusterID:      ci,
+		ClusterName:    pointer.StringDeref(current.Name, ""),
+		CurrentVersion: version,
+	}
+	if current.Kubernetes != nil && current.Kubernetes.ExpirationDate != nil && !time.Time(*current.Kubernetes.ExpirationDate).IsZero() {
+		plan.ExpirationDate = time.Time(*current.Kubernetes.ExpirationDate).Format("2006-01-02")
+	}
+	plan.Targets = append(plan.Targets, targets...)
+
+	return output.New().Print(plan)
+}
+
+func (c *config) clusterUpgradeApply(args []string) error {
+	ci, err := c.clusterID("upgrade apply", args)
+	if err != nil {
+		return err
+	}
+
+	current, constraints, err := c.clusterUpgradeInputs(ci)
+	if err != nil {
+		return err
+	}
+
+	version := clusterKubernetesVersion(current)
+	target := viper.GetString("version")
+	if target == "" {
+		target, err = latestKubernetesVersion(constraints.KubernetesVersions)
+		if err != nil {
+			return err
+		}
+	}
+
+	steps, err := kubernetesUpgradePath(version, target, constraints.KubernetesVersions)
+	if err != nil {
+		return err
+	}
+	if len(steps) == 0 {
+		fmt.Printf("%s cluster %s already runs kubernetes %s\n", color.GreenString("✔"), ci, version)
+		return nil
+	}
+
+	err = enforceClusterUpdatePolicies(current, &models.V1ClusterUpdateRequest{
+		ID:         &ci,
+		Kubernetes: &models.V1Kubernetes{Version: &steps[len(steps)-1]},
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("cluster %s will be upgraded from kubernetes %s in %d step(s): %v\n", pointer.StringDeref(current.Name, ci), version, len(steps), steps)
+	if !viper.GetBool("yes-i-really-mean-it") {
+		err = helper.Prompt("Are you sure? (y/n)", "y")
+		if err != nil {
+			return err
+		}
+	}
+
+	result := current
+	for i, step := range steps {
+		fmt.Printf("step %d/%d: upgrading to kubernetes %s\n", i+1, len(steps), step)
+		step := step
+		request := cluster.NewUpdateClusterParams()
+		request.SetBody(&models.V1ClusterUpdateRequest{
+			ID:         &ci,
+			Kubernetes: &models.V1Kubernetes{Version: &step},
+		})
+		// every step is awaited before the next one, so the last awaited state is the one before the request
+		since := lastOperationTime(result)
+		_, err := c.cloud.Cluster.UpdateCluster(request, nil)
+		if err != nil {
+			return fmt.Errorf("upgrade to kubernetes %s failed: %w", step, err)
+		}
+		result, err = c.waitForCluster(ci, clusterWaitForSucceeded, viper.GetDuration("timeout"), since)
+		if err != nil {
+			return fmt.Errorf("upgrade to kubernetes %s failed: %w", step, err)
+		}
+	}
+
+	return output.New().Print(result)
+}
+
+// clusterUpgradeInputs returns the cluster and the constraints of its partition.
+func (c *config) clusterUpgradeInputs(ci string) (*models.V1ClusterResponse, *models.V1ShootConstraints, error) {
+	findRequest := cluster.NewFindClusterParams().WithID(ci).WithReturnMachines(pointer.BoolPtr(false))
+	resp, err := c.cloud.Cluster.FindCluster(findRequest, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	current := resp.Payload
+
+	request := cluster.NewListConstraintsParams().WithPartition(current.PartitionID)
+	constraints, err := c.cloud.Cluster.ListConstraints(request, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	return current, constraints.Payload, nil
+}
+
+func clusterKubernetesVersion(shoot *models.V1ClusterResponse) string {
+	if shoot.Kubernetes == nil {
+		return ""
+	}
+	return pointer.StringDeref(shoot.Kubernetes.Version, "")
+}
+
+// sortedKubernetesVersions parses and sorts the given versions ascending, unparsable versions are skipped.
+func sortedKubernetesVersions(available []string) []*semver.Version {
+	var versions []*semver.Version
+	for _, a := range available {
+		v, err := semver.NewVersion(a)
+		if err != nil {
+			continue
+		}
+		versions = append(versions, v)
+	}
+	sort.Sort(semver.Collection(versions))
+	return versions
+}
+
+func latestKubernetesVersion(available []string) (string, error) {
+	versions := sortedKubernetesVersions(available)
+	if len(versions) == 0 {
+		return "", fmt.Errorf("no kubernetes versions available")
+	}
+	return versions[len(versions)-1].String(), nil
+}
+
+// kubernetesUpgradeTargets returns the latest patch of the current minor if newer than the current version
+// and the latest patch of every following minor.
+func kubernetesUpgradeTargets(current string, available []string) ([]output.ClusterUpgradeTarget, error) {
+	cv, err := semver.NewVersion(current)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse kubernetes version %q of cluster: %w", current, err)
+	}
+
+	latestPerMinor := map[uint64]*semver.Version{}
+	var minors []uint64
+	for _, v := range sortedKubernetesVersions(available) {
+		if v.Major() != cv.Major() || !v.GreaterThan(cv) {
+			continue
+		}
+		if _, ok := latestPerMinor[v.Minor()]; !ok {
+			minors = append(minors, v.Minor())
+		}
+		latestPerMinor[v.Minor()] = v
+	}
+
+	var targets []output.ClusterUpgradeTarget
+	for _, minor := range minors {
+		t := output.ClusterUpgradeTarget{
+			Type:    output.UpgradeTypeMinor,
+			Version: latestPerMinor[minor].String(),
+		}
+		if minor == cv.Minor() {
+			t.Type = output.UpgradeTypePatch
+		}
+		targets = append(targets, t)
+	}
+	return targets, nil
+}
+
+// kubernetesUpgradePath returns the versions the cluster has to be upgraded to one after the other to reach the target,
+// every minor in between is upgraded to its latest patch.
+func kubernetesUpgradePath(current, target string, available []string) ([]string, error) {
+	cv, err := semver.NewVersion(current)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse kubernetes version %q of cluster: %w", current, err)
+	}
+	tv, err := semver.NewVersion(target)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse kubernetes version %q: %w", target, err)
+	}
+	if tv.LessThan(cv) {
+		return nil, fmt.Errorf("kubernetes version %s is older than the current version %s, downgrades are not possible", target, current)
+	}
+	if tv.Major() != cv.Major() {
+		return nil, fmt.Errorf("upgrades to another major version are not possible")
+	}
+
+	targetAvailable := false
+	for _, v := range sortedKubernetesVersions(available) {
+		if v.Equal(tv) {
+			targetAvailable = true
+			break
+		}
+	}
+	if !targetAvailable {
+		return nil, fmt.Errorf("kubernetes version %s is not available, check cluster inputs for possible values", target)
+	}
+
+	targets, err := kubernetesUpgradeTargets(current, available)
+	if err != nil {
+		return nil, err
+	}
+
+	var steps []string
+	for _, t := range targets {
+		v := semver.MustParse(t.Version)
+		if v.Minor() >= tv.Minor() {
+			break
+		}
+		if t.Type == output.UpgradeTypePatch {
+			// patches of the current minor are included in the upgrade to the next minor
+			continue
+		}
+		steps = append(steps, t.Version)
+	}
+	if !tv.Equal(cv) {
+		steps = append(steps, tv.String())
+	}
+
+	// kubernetes can not skip minor versions, so there must be a version of every minor in between
+	minor := cv.Minor()
+	for _, step := range steps {
+		sv := semver.MustParse(step)
+		if sv.Minor() > minor+1 {
+			return nil, fmt.Errorf("no version of kubernetes %d.%d is available, the upgrade from %s to %s can not skip this minor version", sv.Major(), minor+1, current, target)
+		}
+		minor = sv.Minor()
+	}
+	return steps, nil
+}
@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/fi-ts/cloudctl/cmd/output"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testKubernetesVersions = []string{"1.19.16", "1.20.9", "1.20.15", "1.21.5", "1.21.9", "1.22.6", "1.23.3", "1.23.1"}
+
+func Test_kubernetesUpgradeTargets(t *testing.T) {
+	got, err := kubernetesUpgradeTargets("1.20.9", testKubernetesVersions)
+	require.NoError(t, err)
+	assert.Equal(t, []output.ClusterUpgradeTarget{
+		{Type: output.UpgradeTypePatch, Version: "1.20.15"},
+		{Type: output.UpgradeTypeMinor, Version: "1.21.9"},
+		{Type: output.UpgradeTypeMinor, Version: "1.22.6"},
+		{Type: output.UpgradeTypeMinor, Version: "1.23.3"},
+	}, got)
+
+	got, err = kubernetesUpgradeTargets("1.23.3", testKubernetesVersions)
+	require.NoError(t, err)
+	assert.Empty(t, got)
+}
+
+func Test_kubernetesUpgradePath(t *testing.T) {
+	tests := []struct {
+		name    string
+		current string
+		target  string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name:    "patch only",
+			current: "1.20.9",
+			target:  "1.20.15",
+			want:    []string{"1.20.15"},
+		},
+		{
+			name:    "minor by minor",
+			current: "1.20.9",
+			target:  "1.23.1",
+			want:    []string{"1.21.9", "1.22.6", "1.23.1"},
+		},
+		{
+			name:    "already up to date",
+			current: "1.23.3",
+			target:  "1.23.3",
+			want:    nil,
+		},
+		{
+			name:    "downgrade",
+			current: "1.21.5",
+			target:  "1.20.15",
+			wantErr: true,
+		},
+		{
+			name:    "from an older minor",
+			current: "1.19.16",
+			target:  "1.21.9",
+			want:    []string{"1.20.15", "1.21.9"},
+		},
+		{
+			name:    "unavailable target",
+			current: "1.21.5",
+			target:  "1.22.1",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := kubernetesUpgradePath(tt.current, tt.target, testKubernetesVersions)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_kubernetesUpgradePathSkipsNoMinor(t *testing.T) {
+	_, err := kubernetesUpgradePath("1.20.9", "1.23.3", []string{"1.20.9", "1.21.9", "1.23.3"})
+	assert.EqualError(t, err, "no version of kubernetes 1.22 is available, the upgrade from 1.20.9 to 1.23.3 can not skip this minor version")
+
+	_, err = kubernetesUpgradePath("1.20.9", "1.22.6", []string{"1.20.9", "1.22.6"})
+	assert.EqualError(t, err, "no version of kubernetes 1.21 is available, the upgrade from 1.20.9 to 1.22.6 can not skip this minor version")
+}
@@ -0,0 +1,210 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/fi-ts/cloud-go/api/client/cluster"
+	"github.com/fi-ts/cloud-go/api/models"
+	"github.com/fi-ts/cloudctl/cmd/output"
+	"github.com/gardener/gardener/pkg/apis/core/v1beta1"
+	"github.com/spf13/viper"
+	"k8s.io/utils/pointer"
+)
+
+const (
+	clusterWaitForSucceeded = "succeeded"
+	clusterWaitForHealthy   = "healthy"
+	clusterWaitForDeleted   = "deleted"
+
+	clusterWaitPollInterval   = 10 * time.Second
+	clusterWaitTimeoutDefault = time.Hour
+	// clusterWaitNoOperationDelay is the time after which the operation from before a request is taken as its result,
+	// requests which do not change the cluster do not start a new operation.
+	clusterWaitNoOperationDelay = 2 * time.Minute
+)
+
+var (
+	clusterWaitConditions = []string{clusterWaitForSucceeded, clusterWaitForHealthy, clusterWaitForDeleted}
+
+	// the shoot conditions which are shown while waiting and have to be true for a healthy cluster
+	clusterWaitShootConditions = []struct {
+		conditionType string
+		short         string
+	}{
+		{string(v1beta1.ShootAPIServerAvailable), "Api"},
+		{string(v1beta1.ShootControlPlaneHealthy), "Control"},
+		{string(v1beta1.ShootEveryNodeReady), "Nodes"},
+		{string(v1beta1.ShootSystemComponentsHealthy), "System"},
+	}
+)
+
+func (c *config) clusterWait(args []string) error {
+	ci, err := c.clusterID("wait", args)
+	if err != nil {
+		return err
+	}
+
+	waitFor := viper.GetString("for")
+	timeout := viper.GetDuration("timeout")
+
+	shoot, err := c.waitForCluster(ci, waitFor, timeout, time.Time{})
+	if err != nil {
+		return err
+	}
+	if shoot == nil {
+		return nil
+	}
+	return output.New().Print(shoot)
+}
+
+// waitForClusterIfRequested waits for the given cluster to reach the desired state in case the wait flag is set,
+// the returned cluster should be printed to the user.
+func (c *config) waitForClusterIfRequested(shoot *models.V1ClusterResponse, waitFor string, since time.Time) (*models.V1ClusterResponse, error) {
+	if !viper.GetBool("wait") || shoot == nil || shoot.ID == nil {
+		return shoot, nil
+	}
+	return c.waitForCluster(*shoot.ID, waitFor, viper.GetDuration("timeout"), since)
+}
+
+// waitForCluster polls the given cluster until it reaches the state given in waitFor or the timeout is hit.
+// since is the update time of the last operation of the cluster before the request was sent, see clusterWaitDone.
+func (c *config) waitForCluster(id, waitFor string, timeout time.Duration, since time.Time) (*models.V1ClusterResponse, error) {
+	switch waitFor {
+	case clusterWaitForSucceeded, clusterWaitForHealthy, clusterWaitForDeleted:
+	default:
+		return nil, fmt.Errorf("unable to wait for %q, must be one of %s", waitFor, strings.Join(clusterWaitConditions, "|"))
+	}
+	if timeout <= 0 {
+		timeout = clusterWaitTimeoutDefault
+	}
+
+	started := time.Now()
+	deadline := started.Add(timeout)
+	lastStatus := ""
+	for {
+		findRequest := cluster.NewFindClusterParams().WithID(id).WithReturnMachines(pointer.BoolPtr(false))
+		resp, err := c.cloud.Cluster.FindCluster(findRequest, nil)
+		if err != nil {
+			var r *cluster.FindClusterDefault
+			if errors.As(err, &r) && r.Code() == http.StatusNotFound && waitFor == clusterWaitForDeleted {
+				fmt.Fprintf(os.Stderr, "%s cluster %s is deleted\n", color.GreenString("✔"), id)
+				return nil, nil
+			}
+			return nil, err
+		}
+		shoot := resp.Payload
+
+		status := clusterWaitStatus(shoot)
+		if status != lastStatus {
+			// prefixed with the cluster as several clusters are waited for in parallel with --selector
+			fmt.Fprintf(os.Stderr, "%s %s %s\n", time.Now().Format("15:04:05"), id, status)
+			lastStatus = status
+		}
+
+		done, err := clusterWaitDone(shoot, waitFor, since, time.Since(started))
+		if err != nil {
+			return shoot, err
+		}
+		if done {
+			fmt.Fprintf(os.Stderr, "%s cluster %s is %s\n", color.GreenString("✔"), id, waitFor)
+			return shoot, nil
+		}
+
+		if time.Now().After(deadline) {
+			return shoot, fmt.Errorf("timeout of %s exceeded while waiting for cluster %s to become %s", timeout, id, waitFor)
+		}
+		time.Sleep(clusterWaitPollInterval)
+	}
+}
+
+// clusterWaitDone returns true if the cluster reached the desired state and an error if it never will.
+// an operation which was not updated after since, the update time of the last operation before the request, is only
+// taken as result of the request after the request did not start a new operation for clusterWaitNoOperationDelay.
+// this prevents a successful operation from before an update to be mistaken for the result of the update. since is
+// read from the api like the operations, so a deviating local clock does not matter.
+func clusterWaitDone(shoot *models.V1ClusterResponse, waitFor string, since time.Time, waited time.Duration) (bool, error) {
+	if waitFor == clusterWaitForDeleted {
+		// the cluster is still there, deletion errors are retried by gardener so we keep on waiting
+		return false, nil
+	}
+	if shoot.Status == nil || shoot.Status.LastOperation == nil {
+		return false, nil
+	}
+
+	op := shoot.Status.LastOperation
+	if !since.IsZero() && waited < clusterWaitNoOperationDelay && op.LastUpdateTime != nil {
+		updated, err := time.Parse(time.RFC3339, *op.LastUpdateTime)
+		if err == nil && !updated.After(since) {
+			return false, nil
+		}
+	}
+
+	switch pointer.StringDeref(op.State, "") {
+	case string(v1beta1.LastOperationStateFailed), string(v1beta1.LastOperationStateAborted):
+		return false, fmt.Errorf("%s operation of cluster %s ended with state %s: %s", pointer.StringDeref(op.Type, ""), *shoot.ID, *op.State, pointer.StringDeref(op.Description, ""))
+	case string(v1beta1.LastOperationStateSucceeded):
+	default:
+		return false, nil
+	}
+
+	if waitFor == clusterWaitForSucceeded {
+		return true, nil
+	}
+
+	conditions := map[string]string{}
+	for _, condition := range shoot.Status.Conditions {
+		conditions[pointer.StringDeref(condition.Type, "")] = pointer.StringDeref(condition.Status, "")
+	}
+	for _, sc := range clusterWaitShootConditions {
+		if conditions[sc.conditionType] != string(v1beta1.ConditionTrue) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// lastOperationTime returns the update time of the last operation of the cluster, which is given as since to
+// waitForCluster when the cluster was read before a request. the zero time is returned if there is none.
+func lastOperationTime(shoot *models.V1ClusterResponse) time.Time {
+	if shoot == nil || shoot.Status == nil || shoot.Status.LastOperation == nil || shoot.Status.LastOperation.LastUpdateTime == nil {
+		return time.Time{}
+	}
+	updated, err := time.Parse(time.RFC3339, *shoot.Status.LastOperation.LastUpdateTime)
+	if err != nil {
+		return time.Time{}
+	}
+	return updated
+}
+
+// clusterWaitStatus returns a single line describing the current operation and the shoot conditions
+func clusterWaitStatus(shoot *models.V1ClusterResponse) string {
+	operation := "no operation"
+	conditions := map[string]string{}
+	if shoot.Status != nil {
+		if op := shoot.Status.LastOperation; op != nil {
+			operation = fmt.Sprintf("%s %s %d%%", pointer.StringDeref(op.Type, ""), pointer.StringDeref(op.State, ""), pointer.Int32Deref(op.Progress, 0))
+			if op.Description != nil && *op.Description != "" {
+				operation += " - " + *op.Description
+			}
+		}
+		for _, condition := range shoot.Status.Conditions {
+			conditions[pointer.StringDeref(condition.Type, "")] = pointer.StringDeref(condition.Status, "")
+		}
+	}
+
+	var cs []string
+	for _, sc := range clusterWaitShootConditions {
+		status, ok := conditions[sc.conditionType]
+		if !ok {
+			status = string(v1beta1.ConditionUnknown)
+		}
+		cs = append(cs, sc.short+":"+status)
+	}
+	return fmt.Sprintf("[%s] %s", strings.Join(cs, " "), operation)
+}
@@ -0,0 +1,134 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fi-ts/cloud-go/api/models"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/utils/pointer"
+)
+
+func Test_clusterWaitDone(t *testing.T) {
+	now := time.Now()
+	shoot := func(state string, updated time.Time, conditionStatus string) *models.V1ClusterResponse {
+		var conditions []*models.V1beta1Condition
+		for _, sc := range clusterWaitShootConditions {
+			conditions = append(conditions, &models.V1beta1Condition{
+				Type:   pointer.StringPtr(sc.conditionType),
+				Status: pointer.StringPtr(conditionStatus),
+			})
+		}
+		return &models.V1ClusterResponse{
+			ID: pointer.StringPtr("c1"),
+			Status: &models.V1beta1ShootStatus{
+				LastOperation: &models.V1beta1LastOperation{
+					Type:           pointer.StringPtr("Reconcile"),
+					State:          pointer.StringPtr(state),
+					LastUpdateTime: pointer.StringPtr(updated.Format(time.RFC3339)),
+				},
+				Conditions: conditions,
+			},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		shoot   *models.V1ClusterResponse
+		waitFor string
+		since   time.Time
+		waited  time.Duration
+		want    bool
+		wantErr bool
+	}{
+		{
+			name:    "processing",
+			shoot:   shoot("Processing", now, "True"),
+			waitFor: clusterWaitForSucceeded,
+			want:    false,
+		},
+		{
+			name:    "succeeded",
+			shoot:   shoot("Succeeded", now, "Unknown"),
+			waitFor: clusterWaitForSucceeded,
+			want:    true,
+		},
+		{
+			name:    "succeeded before operation was started",
+			shoot:   shoot("Succeeded", now.Add(-time.Hour), "True"),
+			waitFor: clusterWaitForSucceeded,
+			since:   now.Add(-time.Minute),
+			want:    false,
+		},
+		{
+			name:    "succeeded after the operation before the request",
+			shoot:   shoot("Succeeded", now, "True"),
+			waitFor: clusterWaitForSucceeded,
+			since:   now.Add(-time.Second),
+			want:    true,
+		},
+		{
+			name:    "operation before the request while the request may not have started a new one yet",
+			shoot:   shoot("Succeeded", now, "True"),
+			waitFor: clusterWaitForSucceeded,
+			since:   now,
+			waited:  clusterWaitPollInterval,
+			want:    false,
+		},
+		{
+			name:    "request which started no operation",
+			shoot:   shoot("Succeeded", now, "True"),
+			waitFor: clusterWaitForSucceeded,
+			since:   now,
+			waited:  clusterWaitNoOperationDelay,
+			want:    true,
+		},
+		{
+			name:    "succeeded but not healthy",
+			shoot:   shoot("Succeeded", now, "False"),
+			waitFor: clusterWaitForHealthy,
+			want:    false,
+		},
+		{
+			name:    "healthy",
+			shoot:   shoot("Succeeded", now, "True"),
+			waitFor: clusterWaitForHealthy,
+			want:    true,
+		},
+		{
+			name:    "failed",
+			shoot:   shoot("Failed", now, "True"),
+			waitFor: clusterWaitForSucceeded,
+			wantErr: true,
+		},
+		{
+			name:    "still existing when waiting for deletion",
+			shoot:   shoot("Succeeded", now, "True"),
+			waitFor: clusterWaitForDeleted,
+			want:    false,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := clusterWaitDone(tt.shoot, tt.waitFor, tt.since, tt.waited)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_lastOperationTime(t *testing.T) {
+	updated := time.Date(2021, 11, 4, 10, 0, 0, 0, time.UTC)
+	shoot := &models.V1ClusterResponse{
+		Status: &models.V1beta1ShootStatus{
+			LastOperation: &models.V1beta1LastOperation{LastUpdateTime: pointer.StringPtr(updated.Format(time.RFC3339))},
+		},
+	}
+	assert.True(t, updated.Equal(lastOperationTime(shoot)))
+	assert.True(t, lastOperationTime(&models.V1ClusterResponse{}).IsZero())
+}
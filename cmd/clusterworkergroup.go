@@ -0,0 +1,418 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/fi-ts/cloud-go/api/client/cluster"
+	"github.com/fi-ts/cloud-go/api/models"
+	"github.com/fi-ts/cloudctl/cmd/helper"
+	"github.com/fi-ts/cloudctl/cmd/output"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"k8s.io/utils/pointer"
+)
+
+func newClusterWorkerGroupCmd(c *config) *cobra.Command {
+	workerGroupCmd := &cobra.Command{
+		Use:     "workergroup",
+		Aliases: []string{"workergroups", "wg"},
+		Short:   "manage the worker groups of a cluster",
+	}
+	workerGroupListCmd := &cobra.Command{
+		Use:     "list <clusterid>",
+		Aliases: []string{"ls"},
+		Short:   "list the worker groups of a cluster",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return c.clusterWorkerGroupList(args)
+		},
+		ValidArgsFunction: c.comp.ClusterListCompletion,
+		PreRun:            bindPFlags,
+	}
+	workerGroupAddCmd := &cobra.Command{
+		Use:   "add <clusterid>",
+		Short: "add a worker group to a cluster",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return c.clusterWorkerGroupAdd(args)
+		},
+		ValidArgsFunction: c.comp.ClusterListCompletion,
+		PreRun:            bindPFlags,
+	}
+	workerGroupUpdateCmd := &cobra.Command{
+		Use:   "update <clusterid>",
+		Short: "update a worker group of a cluster",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return c.clusterWorkerGroupUpdate(args)
+		},
+		ValidArgsFunction: c.comp.ClusterListCompletion,
+		PreRun:            bindPFlags,
+	}
+	workerGroupRemoveCmd := &cobra.Command{
+		Use:     "remove <clusterid>",
+		Aliases: []string{"rm", "delete"},
+		Short:   "remove a worker group from a cluster, all nodes of this group are deleted",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return c.clusterWorkerGroupRemove(args)
+		},
+		ValidArgsFunction: c.comp.ClusterListCompletion,
+		PreRun:            bindPFlags,
+	}
+
+	for _, cmd := range []*cobra.Command{workerGroupAddCmd, workerGroupUpdateCmd} {
+		cmd.Flags().String("machinetype", "", "machine type to use for the nodes.")
+		cmd.Flags().String("machineimage", "", "machine image to use for the nodes, must be in the form of <name>-<version>")
+		cmd.Flags().Int32("minsize", 0, "minimal workers of the worker group.")
+		cmd.Flags().Int32("maxsize", 0, "maximal workers of the worker group.")
+		cmd.Flags().String("maxsurge", "", "max number (e.g. 1) or percentage (e.g. 10%) of workers created during a update of the worker group.")
+		cmd.Flags().String("maxunavailable", "", "max number (e.g. 1) or percentage (e.g. 10%) of workers that can be unavailable during a update of the worker group.")
+		cmd.Flags().Duration("healthtimeout", 0, "period (e.g. \"24h\") after which an unhealthy node is declared failed and will be replaced. (0 = provider-default)")
+		cmd.Flags().Duration("draintimeout", 0, "period (e.g. \"3h\") after which a draining node will be forcefully deleted. (0 = provider-default)")
+		must(cmd.RegisterFlagCompletionFunc("machinetype", c.comp.MachineTypeListCompletion))
+		must(cmd.RegisterFlagCompletionFunc("machineimage", c.comp.MachineImageListCompletion))
+	}
+	workerGroupAddCmd.Flags().String("name", "", "name of the worker group. [required]")
+	workerGroupAddCmd.Flags().String("cri", "", "container runtime to use, only docker|containerd supported as alternative actually. defaults to the runtime of the first worker group.")
+	must(workerGroupAddCmd.MarkFlagRequired("name"))
+	must(workerGroupAddCmd.MarkFlagRequired("machinetype"))
+	must(workerGroupAddCmd.RegisterFlagCompletionFunc("cri", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return []string{"docker", "containerd"}, cobra.ShellCompDirectiveNoFileComp
+	}))
+
+	workerGroupUpdateCmd.Flags().String("name", "", "name of the worker group to update, only required when there are multiple worker groups.")
+	workerGroupRemoveCmd.Flags().String("name", "", "name of the worker group to remove. [required]")
+	must(workerGroupRemoveCmd.MarkFlagRequired("name"))
+
+	workerGroupCmd.AddCommand(workerGroupListCmd)
+	workerGroupCmd.AddCommand(workerGroupAddCmd)
+	workerGroupCmd.AddCommand(workerGroupUpdateCmd)
+	workerGroupCmd.AddCommand(workerGroupRemoveCmd)
+
+	return workerGroupCmd
+}
+
+func (c *config) clusterWorkerGroupList(args []string) error {
+	ci, err := c.clusterID("workergroup list", args)
+	if err != nil {
+		return err
+	}
+	current, err := c.findClusterWithoutMachines(ci)
+	if err != nil {
+		return err
+	}
+	return output.New().Print(current.Workers)
+}
+
+func (c *config) clusterWorkerGroupAdd(args []string) error {
+	ci, err := c.clusterID("workergroup add", args)
+	if err != nil {
+		return err
+	}
+	current, err := c.findClusterWithoutMachines(ci)
+	if err != nil {
+		return err
+	}
+
+	name := viper.GetString("name")
+	workers := copyWorkers(current.Workers)
+
+	// machine image and cri default to the ones of the first worker group, the other values to a group of a single
+	// node which is replaced one by one
+	worker := &models.V1Worker{
+		Name:           &name,
+		Minimum:        pointer.Int32Ptr(1),
+		Maximum:        pointer.Int32Ptr(1),
+		MaxSurge:       pointer.StringPtr("1"),
+		MaxUnavailable: pointer.StringPtr("1"),
+		CRI:            pointer.StringPtr(""),
+	}
+	if len(workers) > 0 {
+		worker.MachineImage = workers[0].MachineImage
+		worker.CRI = workers[0].CRI
+	}
+	if cri := viper.GetString("cri"); cri != "" {
+		worker.CRI = &cri
+	}
+
+	err = updateWorkerFromFlags(worker, current)
+	if err != nil {
+		return err
+	}
+
+	workers, err = addWorkerGroup(workers, worker)
+	if err != nil {
+		return err
+	}
+
+	return c.updateClusterWorkers(current, workers, worker)
+}
+
+func (c *config) clusterWorkerGroupUpdate(args []string) error {
+	ci, err := c.clusterID("workergroup update", args)
+	if err != nil {
+		return err
+	}
+	current, err := c.findClusterWithoutMachines(ci)
+	if err != nil {
+		return err
+	}
+
+	if !workerFlagsGiven() {
+		return fmt.Errorf("nothing to update, please provide at least one of the worker group flags")
+	}
+
+	workers := copyWorkers(current.Workers)
+	worker, err := findWorkerGroup(workers, viper.GetString("name"), "name")
+	if err != nil {
+		return err
+	}
+
+	err = updateWorkerFromFlags(worker, current)
+	if err != nil {
+		return err
+	}
+
+	return c.updateClusterWorkers(current, workers, worker)
+}
+
+func (c *config) clusterWorkerGroupRemove(args []string) error {
+	ci, err := c.clusterID("workergroup remove", args)
+	if err != nil {
+		return err
+	}
+	current, err := c.findClusterWithoutMachines(ci)
+	if err != nil {
+		return err
+	}
+
+	name := viper.GetString("name")
+	workers, err := removeWorkerGroup(copyWorkers(current.Workers), name)
+	if err != nil {
+		return err
+	}
+
+	if !viper.GetBool("yes-i-really-mean-it") {
+		fmt.Printf("All nodes of worker group %s will be deleted.\n", name)
+		err = helper.Prompt("Are you sure? (y/n)", "y")
+		if err != nil {
+			return err
+		}
+	}
+
+	return c.updateClusterWorkers(current, workers, nil)
+}
+
+// updateClusterWorkers replaces the worker groups of the cluster with the given ones. only the changed worker group
+// is validated against the constraints of the cluster partition, other groups may still use a machine image which
+// left the constraints and must not block changes, e.g. removing such a group. changed is nil on removals.
+func (c *config) updateClusterWorkers(current *models.V1ClusterResponse, workers []*models.V1Worker, changed *models.V1Worker) error {
+	cur := &models.V1ClusterUpdateRequest{
+		ID:         current.ID,
+		Workers:    workers,
+		Kubernetes: &models.V1Kubernetes{},
+	}
+	err := enforceClusterUpdatePolicies(current, cur)
+	if err != nil {
+		return err
+	}
+
+	if changed != nil {
+		request := cluster.NewListConstraintsParams().WithPartition(current.PartitionID)
+		constraints, err := c.cloud.Cluster.ListConstraints(request, nil)
+		if err != nil {
+			return err
+		}
+		err = validateWorkerGroup(changed, constraints.Payload)
+		if err != nil {
+			return err
+		}
+	}
+
+	params := cluster.NewUpdateClusterParams()
+	params.SetBody(cur)
+	shoot, err := c.cloud.Cluster.UpdateCluster(params, nil)
+	if err != nil {
+		return err
+	}
+	return output.New().Print(shoot.Payload.Workers)
+}
+
+func (c *config) findClusterWithoutMachines(id string) (*models.V1ClusterResponse, error) {
+	findRequest := cluster.NewFindClusterParams().WithID(id).WithReturnMachines(pointer.BoolPtr(false))
+	resp, err := c.cloud.Cluster.FindCluster(findRequest, nil)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Payload, nil
+}
+
+// validateWorkerGroup checks the worker group against the constraints of the partition
+func validateWorkerGroup(w *models.V1Worker, constraints *models.V1ShootConstraints) error {
+	name := pointer.StringDeref(w.Name, "")
+	if w.Minimum == nil || w.Maximum == nil || *w.Minimum > *w.Maximum {
+		return fmt.Errorf("minimum of worker group %s must not be greater than its maximum", name)
+	}
+	if *w.Maximum < 1 {
+		return fmt.Errorf("maximum of worker group %s must be at least 1", name)
+	}
+
+	machineType := pointer.StringDeref(w.MachineType, "")
+	validType := false
+	for _, t := range constraints.MachineTypes {
+		if t == machineType {
+			validType = true
+			break
+		}
+	}
+	if !validType {
+		return fmt.Errorf("machine type %q of worker group %s is not available in this partition, choose one of %s", machineType, name, strings.Join(constraints.MachineTypes, "|"))
+	}
+
+	if w.MachineImage == nil {
+		return fmt.Errorf("worker group %s has no machine image", name)
+	}
+	image := pointer.StringDeref(w.MachineImage.Name, "") + "-" + pointer.StringDeref(w.MachineImage.Version, "")
+	var images []string
+	for _, i := range constraints.MachineImages {
+		candidate := pointer.StringDeref(i.Name, "") + "-" + pointer.StringDeref(i.Version, "")
+		if candidate == image {
+			return nil
+		}
+		images = append(images, candidate)
+	}
+	return fmt.Errorf("machine image %q of worker group %s is not available in this partition, choose one of %s", image, name, strings.Join(images, "|"))
+}
+
+// workerFlagsGiven returns true if any flag is given which modifies a worker group
+func workerFlagsGiven() bool {
+	return viper.GetInt32("minsize") != 0 || viper.GetInt32("maxsize") != 0 ||
+		viper.GetString("machineimage") != "" || viper.GetString("machinetype") != "" ||
+		viper.IsSet("healthtimeout") || viper.IsSet("draintimeout") ||
+		viper.GetString("maxsurge") != "" || viper.GetString("maxunavailable") != ""
+}
+
+// updateWorkerFromFlags applies the worker group flags to the given worker
+func updateWorkerFromFlags(worker *models.V1Worker, current *models.V1ClusterResponse) error {
+	minsize := viper.GetInt32("minsize")
+	maxsize := viper.GetInt32("maxsize")
+	machineType := viper.GetString("machinetype")
+	machineImageAndVersion := viper.GetString("machineimage")
+	maxsurge := viper.GetString("maxsurge")
+	maxunavailable := viper.GetString("maxunavailable")
+
+	if minsize != 0 {
+		worker.Minimum = &minsize
+	}
+	if maxsize != 0 {
+		worker.Maximum = &maxsize
+	}
+
+	if machineImageAndVersion != "" {
+		machineImage, err := parseMachineImage(machineImageAndVersion)
+		if err != nil {
+			return err
+		}
+		worker.MachineImage = machineImage
+	}
+
+	if machineType != "" {
+		worker.MachineType = &machineType
+	}
+
+	mcmMigrated := false
+	for _, feature := range current.ControlPlaneFeatureGates {
+		if feature == "machineControllerManagerOOT" {
+			mcmMigrated = true
+			break
+		}
+	}
+
+	if viper.IsSet("healthtimeout") {
+		if !mcmMigrated {
+			return fmt.Errorf("custom healthtimeout requires feature: machineControllerManagerOOT")
+		}
+		worker.HealthTimeout = int64(viper.GetDuration("healthtimeout"))
+	}
+
+	if viper.IsSet("draintimeout") {
+		if !mcmMigrated {
+			return fmt.Errorf("custom draintimeout requires feature: machineControllerManagerOOT")
+		}
+		worker.DrainTimeout = int64(viper.GetDuration("draintimeout"))
+	}
+
+	if maxsurge != "" {
+		worker.MaxSurge = &maxsurge
+	}
+
+	if maxunavailable != "" {
+		worker.MaxUnavailable = &maxunavailable
+	}
+
+	return nil
+}
+
+// findWorkerGroup returns the worker group with the given name, the name can be omitted if there is only one group
+func findWorkerGroup(workers []*models.V1Worker, name, flag string) (*models.V1Worker, error) {
+	if name != "" {
+		for _, w := range workers {
+			if w.Name != nil && *w.Name == name {
+				return w, nil
+			}
+		}
+		return nil, fmt.Errorf("no worker group found by name: %s", name)
+	}
+	if len(workers) == 1 {
+		return workers[0], nil
+	}
+	return nil, fmt.Errorf("there are multiple worker groups, please specify the worker group you want to update with --%s", flag)
+}
+
+// addWorkerGroup appends the worker group to the given worker groups, names of worker groups must be unique
+func addWorkerGroup(workers []*models.V1Worker, worker *models.V1Worker) ([]*models.V1Worker, error) {
+	name := pointer.StringDeref(worker.Name, "")
+	for _, w := range workers {
+		if pointer.StringDeref(w.Name, "") == name {
+			return nil, fmt.Errorf("worker group %s already exists", name)
+		}
+	}
+	return append(workers, worker), nil
+}
+
+// removeWorkerGroup returns the given worker groups without the one with the given name
+func removeWorkerGroup(workers []*models.V1Worker, name string) ([]*models.V1Worker, error) {
+	var result []*models.V1Worker
+	for _, w := range workers {
+		if pointer.StringDeref(w.Name, "") == name {
+			continue
+		}
+		result = append(result, w)
+	}
+	if len(result) == len(workers) {
+		return nil, fmt.Errorf("no worker group found by name: %s", name)
+	}
+	if len(result) == 0 {
+		return nil, fmt.Errorf("the last worker group of a cluster can not be removed")
+	}
+	return result, nil
+}
+
+func copyWorkers(workers []*models.V1Worker) []*models.V1Worker {
+	var result []*models.V1Worker
+	for _, w := range workers {
+		worker := *w
+		result = append(result, &worker)
+	}
+	return result
+}
+
+// parseMachineImage parses a machine image in the form of <name>-<version>
+func parseMachineImage(machineImageAndVersion string) (*models.V1MachineImage, error) {
+	machineImageParts := strings.Split(machineImageAndVersion, "-")
+	if len(machineImageParts) != 2 {
+		return nil, fmt.Errorf("given machineimage:%s is invalid must be in the form <name>-<version>", machineImageAndVersion)
+	}
+	return &models.V1MachineImage{
+		Name:    &machineImageParts[0],
+		Version: &machineImageParts[1],
+	}, nil
+}
@@ -0,0 +1,302 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fi-ts/cloud-go/api/client"
+	"github.com/fi-ts/cloud-go/api/client/cluster"
+	"github.com/fi-ts/cloud-go/api/models"
+	mockcluster "github.com/fi-ts/cloud-go/test/mocks/cluster"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"k8s.io/utils/pointer"
+)
+
+func Test_validateWorkerGroup(t *testing.T) {
+	constraints := &models.V1ShootConstraints{
+		MachineTypes: []string{"c1-xlarge-x86", "c1-large-x86"},
+		MachineImages: []*models.V1MachineImage{
+			{Name: pointer.StringPtr("ubuntu"), Version: pointer.StringPtr("20.04")},
+			{Name: pointer.StringPtr("debian"), Version: pointer.StringPtr("10")},
+		},
+	}
+	worker := func(modify func(w *models.V1Worker)) *models.V1Worker {
+		w := &models.V1Worker{
+			Name:         pointer.StringPtr("group-0"),
+			MachineType:  pointer.StringPtr("c1-xlarge-x86"),
+			MachineImage: &models.V1MachineImage{Name: pointer.StringPtr("ubuntu"), Version: pointer.StringPtr("20.04")},
+			Minimum:      pointer.Int32Ptr(1),
+			Maximum:      pointer.Int32Ptr(3),
+		}
+		if modify != nil {
+			modify(w)
+		}
+		return w
+	}
+
+	tests := []struct {
+		name    string
+		worker  *models.V1Worker
+		wantErr string
+	}{
+		{
+			name:   "valid worker group",
+			worker: worker(nil),
+		},
+		{
+			name:    "minimum greater than maximum",
+			worker:  worker(func(w *models.V1Worker) { w.Minimum = pointer.Int32Ptr(4) }),
+			wantErr: "minimum of worker group group-0 must not be greater than its maximum",
+		},
+		{
+			name:    "minimum not set",
+			worker:  worker(func(w *models.V1Worker) { w.Minimum = nil }),
+			wantErr: "minimum of worker group group-0 must not be greater than its maximum",
+		},
+		{
+			name:    "maximum of zero",
+			worker:  worker(func(w *models.V1Worker) { w.Minimum = pointer.Int32Ptr(0); w.Maximum = pointer.Int32Ptr(0) }),
+			wantErr: "maximum of worker group group-0 must be at least 1",
+		},
+		{
+			name:    "unknown machine type",
+			worker:  worker(func(w *models.V1Worker) { w.MachineType = pointer.StringPtr("n1-medium-x86") }),
+			wantErr: `machine type "n1-medium-x86" of worker group group-0 is not available in this partition, choose one of c1-xlarge-x86|c1-large-x86`,
+		},
+		{
+			name:    "no machine image",
+			worker:  worker(func(w *models.V1Worker) { w.MachineImage = nil }),
+			wantErr: "worker group group-0 has no machine image",
+		},
+		{
+			name: "unknown machine image",
+			worker: worker(func(w *models.V1Worker) {
+				w.MachineImage = &models.V1MachineImage{Name: pointer.StringPtr("ubuntu"), Version: pointer.StringPtr("18.04")}
+			}),
+			wantErr: `machine image "ubuntu-18.04" of worker group group-0 is not available in this partition, choose one of ubuntu-20.04|debian-10`,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateWorkerGroup(tt.worker, constraints)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func Test_addWorkerGroup(t *testing.T) {
+	workers := []*models.V1Worker{{Name: pointer.StringPtr("group-0")}}
+
+	got, err := addWorkerGroup(workers, &models.V1Worker{Name: pointer.StringPtr("group-1")})
+	require.NoError(t, err)
+	assert.Equal(t, []*models.V1Worker{{Name: pointer.StringPtr("group-0")}, {Name: pointer.StringPtr("group-1")}}, got)
+
+	_, err = addWorkerGroup(workers, &models.V1Worker{Name: pointer.StringPtr("group-0")})
+	assert.EqualError(t, err, "worker group group-0 already exists")
+}
+
+func Test_removeWorkerGroup(t *testing.T) {
+	tests := []struct {
+		name    string
+		workers []*models.V1Worker
+		remove  string
+		want    []*models.V1Worker
+		wantErr string
+	}{
+		{
+			name:    "remove a worker group",
+			workers: []*models.V1Worker{{Name: pointer.StringPtr("group-0")}, {Name: pointer.StringPtr("group-1")}},
+			remove:  "group-0",
+			want:    []*models.V1Worker{{Name: pointer.StringPtr("group-1")}},
+		},
+		{
+			name:    "unknown worker group",
+			workers: []*models.V1Worker{{Name: pointer.StringPtr("group-0")}, {Name: pointer.StringPtr("group-1")}},
+			remove:  "group-2",
+			wantErr: "no worker group found by name: group-2",
+		},
+		{
+			name:    "last worker group",
+			workers: []*models.V1Worker{{Name: pointer.StringPtr("group-0")}},
+			remove:  "group-0",
+			wantErr: "the last worker group of a cluster can not be removed",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := removeWorkerGroup(tt.workers, tt.remove)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_updateWorkerFromFlags(t *testing.T) {
+	current := &models.V1ClusterResponse{ControlPlaneFeatureGates: []string{"machineControllerManagerOOT"}}
+	worker := func() *models.V1Worker {
+		return &models.V1Worker{
+			Name:           pointer.StringPtr("group-0"),
+			MachineType:    pointer.StringPtr("c1-xlarge-x86"),
+			MachineImage:   &models.V1MachineImage{Name: pointer.StringPtr("ubuntu"), Version: pointer.StringPtr("20.04")},
+			Minimum:        pointer.Int32Ptr(1),
+			Maximum:        pointer.Int32Ptr(3),
+			MaxSurge:       pointer.StringPtr("1"),
+			MaxUnavailable: pointer.StringPtr("0"),
+		}
+	}
+
+	tests := []struct {
+		name    string
+		flags   map[string]interface{}
+		current *models.V1ClusterResponse
+		want    *models.V1Worker
+		wantErr string
+	}{
+		{
+			name:    "no flags keep the worker group",
+			current: current,
+			want:    worker(),
+		},
+		{
+			name:    "given flags override the worker group",
+			flags:   map[string]interface{}{"maxsize": int32(5), "machineimage": "debian-10", "maxsurge": "2", "healthtimeout": time.Hour},
+			current: current,
+			want: func() *models.V1Worker {
+				w := worker()
+				w.Maximum = pointer.Int32Ptr(5)
+				w.MachineImage = &models.V1MachineImage{Name: pointer.StringPtr("debian"), Version: pointer.StringPtr("10")}
+				w.MaxSurge = pointer.StringPtr("2")
+				w.HealthTimeout = int64(time.Hour)
+				return w
+			}(),
+		},
+		{
+			name:    "invalid machine image",
+			flags:   map[string]interface{}{"machineimage": "ubuntu"},
+			current: current,
+			wantErr: "given machineimage:ubuntu is invalid must be in the form <name>-<version>",
+		},
+		{
+			name:    "draintimeout requires the out of tree machine controller manager",
+			flags:   map[string]interface{}{"draintimeout": time.Hour},
+			current: &models.V1ClusterResponse{},
+			wantErr: "custom draintimeout requires feature: machineControllerManagerOOT",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Cleanup(viper.Reset)
+			for k, v := range tt.flags {
+				viper.Set(k, v)
+			}
+
+			got := worker()
+			err := updateWorkerFromFlags(got, tt.current)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_updateClusterWorkersEnforcesPolicies(t *testing.T) {
+	useTestPolicies(t)
+
+	current := &models.V1ClusterResponse{
+		ID:      pointer.StringPtr("c1"),
+		Name:    pointer.StringPtr("shop"),
+		Purpose: pointer.StringPtr("production"),
+		Labels:  map[string]string{"team": "a"},
+		Workers: []*models.V1Worker{{Name: pointer.StringPtr("group-0"), Minimum: pointer.Int32Ptr(3)}},
+	}
+	workers := []*models.V1Worker{{Name: pointer.StringPtr("group-0"), Minimum: pointer.Int32Ptr(1)}}
+
+	// the violation has to be found before the api is called, which is not configured here
+	c := &config{}
+	err := c.updateClusterWorkers(current, workers, workers[0])
+	assert.EqualError(t, err, "cluster shop violates policies:\n  policy production-size (hard): worker group group-0 has a minsize of 1, at least 3 is required")
+}
+
+func Test_updateClusterWorkersValidatesOnlyTheChangedGroup(t *testing.T) {
+	outdated := &models.V1Worker{
+		Name:         pointer.StringPtr("group-0"),
+		MachineType:  pointer.StringPtr("c1-xlarge-x86"),
+		MachineImage: &models.V1MachineImage{Name: pointer.StringPtr("ubuntu"), Version: pointer.StringPtr("19.10")},
+		Minimum:      pointer.Int32Ptr(1),
+		Maximum:      pointer.Int32Ptr(1),
+	}
+	changed := &models.V1Worker{
+		Name:         pointer.StringPtr("group-1"),
+		MachineType:  pointer.StringPtr("c1-xlarge-x86"),
+		MachineImage: &models.V1MachineImage{Name: pointer.StringPtr("ubuntu"), Version: pointer.StringPtr("20.04")},
+		Minimum:      pointer.Int32Ptr(1),
+		Maximum:      pointer.Int32Ptr(2),
+	}
+	current := &models.V1ClusterResponse{
+		ID:          pointer.StringPtr("c1"),
+		PartitionID: pointer.StringPtr("nbg-w8101"),
+		Workers:     []*models.V1Worker{outdated, changed},
+	}
+	constraints := &models.V1ShootConstraints{
+		MachineTypes:  []string{"c1-xlarge-x86"},
+		MachineImages: []*models.V1MachineImage{{Name: pointer.StringPtr("ubuntu"), Version: pointer.StringPtr("20.04")}},
+	}
+
+	t.Run("update of another group", func(t *testing.T) {
+		mockClusterService := new(mockcluster.ClientService)
+		mockClusterService.On("ListConstraints", mock.Anything, mock.Anything).Return(&cluster.ListConstraintsOK{Payload: constraints}, nil)
+		mockClusterService.On("UpdateCluster", mock.Anything, mock.Anything).Return(&cluster.UpdateClusterOK{Payload: current}, nil)
+		c := &config{cloud: &client.CloudAPI{Cluster: mockClusterService}}
+
+		require.NoError(t, c.updateClusterWorkers(current, []*models.V1Worker{outdated, changed}, changed))
+		mockClusterService.AssertExpectations(t)
+	})
+
+	t.Run("removal of the outdated group", func(t *testing.T) {
+		mockClusterService := new(mockcluster.ClientService)
+		mockClusterService.On("UpdateCluster", mock.Anything, mock.Anything).Return(&cluster.UpdateClusterOK{Payload: current}, nil)
+		c := &config{cloud: &client.CloudAPI{Cluster: mockClusterService}}
+
+		require.NoError(t, c.updateClusterWorkers(current, []*models.V1Worker{changed}, nil))
+		mockClusterService.AssertExpectations(t)
+		mockClusterService.AssertNotCalled(t, "ListConstraints", mock.Anything, mock.Anything)
+	})
+
+	t.Run("invalid changed group", func(t *testing.T) {
+		mockClusterService := new(mockcluster.ClientService)
+		mockClusterService.On("ListConstraints", mock.Anything, mock.Anything).Return(&cluster.ListConstraintsOK{Payload: constraints}, nil)
+		c := &config{cloud: &client.CloudAPI{Cluster: mockClusterService}}
+
+		err := c.updateClusterWorkers(current, []*models.V1Worker{outdated, changed}, outdated)
+		assert.EqualError(t, err, `machine image "ubuntu-19.10" of worker group group-0 is not available in this partition, choose one of ubuntu-20.04`)
+		mockClusterService.AssertNotCalled(t, "UpdateCluster", mock.Anything, mock.Anything)
+	})
+}
+
+func Test_parseMachineImage(t *testing.T) {
+	got, err := parseMachineImage("ubuntu-20.04")
+	require.NoError(t, err)
+	assert.Equal(t, &models.V1MachineImage{Name: pointer.StringPtr("ubuntu"), Version: pointer.StringPtr("20.04")}, got)
+
+	for _, invalid := range []string{"ubuntu", "ubuntu-20.04-1", ""} {
+		_, err := parseMachineImage(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
@@ -2,12 +2,26 @@ package helper
 
 import (
 	"fmt"
+	"net/url"
+	"strings"
 
+	"github.com/gosimple/slug"
+	"github.com/icza/dyno"
 	"github.com/metal-stack/metal-lib/auth"
 	"gopkg.in/yaml.v3"
 )
 
-func EnrichKubeconfigTpl(tpl string, authContext *auth.AuthContext) ([]byte, error) {
+// ExecCredentialUser is a kubeconfig user which obtains its token from an exec credential plugin
+// instead of embedding the current token.
+type ExecCredentialUser struct {
+	Name        string
+	APIVersion  string
+	Command     string
+	Args        []string
+	InstallHint string
+}
+
+func EnrichKubeconfigTpl(tpl string, authContext *auth.AuthContext, execUser *ExecCredentialUser) ([]byte, error) {
 	cfg := make(map[interface{}]interface{})
 	err := yaml.Unmarshal([]byte(tpl), cfg)
 	if err != nil {
@@ -22,15 +36,14 @@ func EnrichKubeconfigTpl(tpl string, authContext *auth.AuthContext) ([]byte, err
 		return nil, fmt.Errorf("expected one cluster in config, got %d", len(clusterNames))
 	}
 
-	userName := authContext.User
-	clusterName := clusterNames[0]
-	contextName := fmt.Sprintf("%s@%s", userName, clusterName)
-
 	// merge with current user credentials
-	err = auth.AddUser(cfg, *authContext)
+	userName, err := addUser(cfg, authContext, execUser)
 	if err != nil {
 		return nil, err
 	}
+	clusterName := clusterNames[0]
+	contextName := fmt.Sprintf("%s@%s", userName, clusterName)
+
 	err = auth.AddContext(cfg, contextName, clusterName, userName)
 	if err != nil {
 		return nil, err
@@ -45,7 +58,19 @@ func EnrichKubeconfigTpl(tpl string, authContext *auth.AuthContext) ([]byte, err
 	return mergedKubeconfig, nil
 }
 
-func MergeKubeconfigTpl(currentCfg map[interface{}]interface{}, tpl, contextName, clusterName string, authContext *auth.AuthContext) ([]byte, error) {
+// KubeconfigExtensionName is the name of the extension cloudctl adds to the contexts it merges into a kubeconfig,
+// it is used to find these contexts again, e.g. for pruning contexts of deleted clusters.
+const KubeconfigExtensionName = "cloudctl"
+
+// KubeconfigExtension references the cluster a merged context belongs to.
+type KubeconfigExtension struct {
+	// ClusterID is the id of the cluster
+	ClusterID string
+	// CloudContext is the cloudctl context that was active when the context was merged
+	CloudContext string
+}
+
+func MergeKubeconfigTpl(currentCfg map[interface{}]interface{}, tpl, contextName, clusterName string, authContext *auth.AuthContext, execUser *ExecCredentialUser, extension KubeconfigExtension) ([]byte, error) {
 	clusters := &struct {
 		Clusters []struct {
 			Cluster map[string]interface{} `yaml:"cluster"`
@@ -64,11 +89,11 @@ func MergeKubeconfigTpl(currentCfg map[interface{}]interface{}, tpl, contextName
 	if err != nil {
 		return nil, err
 	}
-	err = auth.AddUser(currentCfg, *authContext)
+	userName, err := addUser(currentCfg, authContext, execUser)
 	if err != nil {
 		return nil, err
 	}
-	err = auth.AddContext(currentCfg, contextName, clusterName, authContext.User)
+	err = addContextWithExtension(currentCfg, contextName, clusterName, userName, extension)
 	if err != nil {
 		return nil, err
 	}
@@ -80,3 +105,250 @@ func MergeKubeconfigTpl(currentCfg map[interface{}]interface{}, tpl, contextName
 
 	return mergedKubeconfig, nil
 }
+
+// addUser adds the exec credential user if given, otherwise the user of the auth context with its current token.
+func addUser(cfg map[interface{}]interface{}, authContext *auth.AuthContext, execUser *ExecCredentialUser) (string, error) {
+	if execUser == nil {
+		return authContext.User, auth.AddUser(cfg, *authContext)
+	}
+
+	args := []interface{}{}
+	for _, a := range execUser.Args {
+		args = append(args, a)
+	}
+	user := map[string]interface{}{
+		"name": execUser.Name,
+		"user": map[string]interface{}{
+			"exec": map[string]interface{}{
+				"apiVersion":  execUser.APIVersion,
+				"command":     execUser.Command,
+				"args":        args,
+				"installHint": execUser.InstallHint,
+			},
+		},
+	}
+
+	if _, ok := cfg["users"]; !ok {
+		cfg["users"] = []interface{}{user}
+		return execUser.Name, nil
+	}
+	users, err := dyno.GetSlice(cfg, "users")
+	if err != nil {
+		return "", err
+	}
+	for i := range users {
+		m, err := dyno.GetMapS(users[i])
+		if err != nil {
+			continue
+		}
+		if m["name"] == execUser.Name {
+			users[i] = user
+			return execUser.Name, nil
+		}
+	}
+	cfg["users"] = append(users, user)
+	return execUser.Name, nil
+}
+
+// addContextWithExtension adds or replaces the named context like auth.AddContext does
+// and marks it with the cloudctl extension.
+func addContextWithExtension(cfg map[interface{}]interface{}, contextName, clusterName, userName string, extension KubeconfigExtension) error {
+	context := map[string]interface{}{
+		"name": contextName,
+		"context": map[string]interface{}{
+			"cluster": clusterName,
+			"user":    userName,
+			"extensions": []interface{}{
+				map[string]interface{}{
+					"name": KubeconfigExtensionName,
+					"extension": map[string]interface{}{
+						"cluster-id":    extension.ClusterID,
+						"cloud-context": extension.CloudContext,
+					},
+				},
+			},
+		},
+	}
+
+	if _, ok := cfg["contexts"]; !ok {
+		cfg["contexts"] = []interface{}{context}
+		return nil
+	}
+	contexts, err := dyno.GetSlice(cfg, "contexts")
+	if err != nil {
+		return err
+	}
+	for i := range contexts {
+		m, err := dyno.GetMapS(contexts[i])
+		if err != nil {
+			continue
+		}
+		if m["name"] == contextName {
+			contexts[i] = context
+			return nil
+		}
+	}
+	cfg["contexts"] = append(contexts, context)
+	return nil
+}
+
+// PrunedContext is a context removed from a kubeconfig by PruneKubeconfig.
+type PrunedContext struct {
+	Name        string
+	ClusterName string
+	ClusterID   string
+	// Server is the api server of a context merged before cloudctl marked its contexts with the extension,
+	// these contexts are recognized by their naming and api server as they do not contain the cluster id.
+	Server string
+}
+
+// ExistingClusters are the clusters of a cloud context which still exist.
+type ExistingClusters struct {
+	// IDs are the ids of the clusters
+	IDs map[string]bool
+	// Endpoints are the hosts of the api servers of the clusters
+	Endpoints map[string]bool
+}
+
+// PruneKubeconfig removes all contexts merged by cloudctl for the given cloud context whose cluster does not exist
+// anymore. Cluster entries are only removed if no other context references them anymore.
+//
+// Contexts merged before cloudctl marked them with the extension are recognized by the naming "cluster kubeconfig
+// --merge" has always used, the context is the slug of the cluster name, and by their api server, which is
+// api.<cluster name>.<project>.<domain> with a domain of one of the existing clusters. They are removed if no
+// existing cluster has this api server. All other contexts are left untouched.
+func PruneKubeconfig(cfg map[interface{}]interface{}, cloudContext string, existing ExistingClusters) ([]PrunedContext, error) {
+	contexts, err := dyno.GetSlice(cfg, "contexts")
+	if err != nil {
+		// no contexts, nothing to prune
+		return nil, nil
+	}
+
+	servers := map[string]string{}
+	clusters, _ := dyno.GetSlice(cfg, "clusters")
+	for _, c := range clusters {
+		m, err := dyno.GetMapS(c)
+		if err != nil {
+			continue
+		}
+		name, _ := m["name"].(string)
+		server, _ := dyno.GetString(m, "cluster", "server")
+		servers[name] = server
+	}
+	domains := map[string]bool{}
+	for endpoint := range existing.Endpoints {
+		if domain, ok := apiServerDomain(endpoint, ""); ok {
+			domains[domain] = true
+		}
+	}
+
+	var (
+		pruned     []PrunedContext
+		remaining  []interface{}
+		referenced = map[string]bool{}
+	)
+	for _, c := range contexts {
+		m, err := dyno.GetMapS(c)
+		if err != nil {
+			remaining = append(remaining, c)
+			continue
+		}
+		name, _ := m["name"].(string)
+		clusterName, _ := dyno.GetString(m, "context", "cluster")
+
+		if ext, ok := contextExtension(m); ok {
+			if ext.CloudContext == cloudContext && !existing.IDs[ext.ClusterID] {
+				pruned = append(pruned, PrunedContext{Name: name, ClusterName: clusterName, ClusterID: ext.ClusterID})
+				continue
+			}
+		} else if server := servers[clusterName]; legacyContext(name, clusterName, server, domains) && !existing.Endpoints[KubeconfigServerHost(server)] {
+			pruned = append(pruned, PrunedContext{Name: name, ClusterName: clusterName, Server: server})
+			continue
+		}
+		remaining = append(remaining, c)
+		referenced[clusterName] = true
+	}
+	if len(pruned) == 0 {
+		return nil, nil
+	}
+	cfg["contexts"] = remaining
+
+	removeClusters := map[string]bool{}
+	for _, p := range pruned {
+		if !referenced[p.ClusterName] {
+			removeClusters[p.ClusterName] = true
+		}
+		if current, _ := cfg["current-context"].(string); current == p.Name {
+			cfg["current-context"] = ""
+		}
+	}
+
+	if clusters == nil {
+		return pruned, nil
+	}
+	var remainingClusters []interface{}
+	for _, c := range clusters {
+		m, err := dyno.GetMapS(c)
+		if err == nil {
+			if name, _ := m["name"].(string); removeClusters[name] {
+				continue
+			}
+		}
+		remainingClusters = append(remainingClusters, c)
+	}
+	cfg["clusters"] = remainingClusters
+
+	return pruned, nil
+}
+
+// legacyContext returns true if the context was merged by cloudctl into the kubeconfig before the extension was added,
+// the context is named like "cluster kubeconfig --merge" names it and the api server is in one of the given domains.
+func legacyContext(name, clusterName, server string, domains map[string]bool) bool {
+	if clusterName == "" || name != slug.Make(clusterName) {
+		return false
+	}
+	domain, ok := apiServerDomain(KubeconfigServerHost(server), clusterName)
+	return ok && domains[domain]
+}
+
+// apiServerDomain returns the domain of the api server host of a cluster, which is api.<cluster name>.<project>.<domain>.
+// if clusterName is given, the host has to belong to this cluster.
+func apiServerDomain(host, clusterName string) (string, bool) {
+	parts := strings.SplitN(host, ".", 4)
+	if len(parts) != 4 || parts[0] != "api" {
+		return "", false
+	}
+	if clusterName != "" && parts[1] != clusterName {
+		return "", false
+	}
+	return parts[3], true
+}
+
+// KubeconfigServerHost returns the host of the given api server url.
+func KubeconfigServerHost(server string) string {
+	u, err := url.Parse(server)
+	if err != nil || u.Host == "" {
+		return server
+	}
+	return u.Hostname()
+}
+
+func contextExtension(context map[string]interface{}) (*KubeconfigExtension, bool) {
+	extensions, err := dyno.GetSlice(context, "context", "extensions")
+	if err != nil {
+		return nil, false
+	}
+	for _, e := range extensions {
+		m, err := dyno.GetMapS(e)
+		if err != nil || m["name"] != KubeconfigExtensionName {
+			continue
+		}
+		clusterID, err := dyno.GetString(m, "extension", "cluster-id")
+		if err != nil || clusterID == "" {
+			return nil, false
+		}
+		cloudContext, _ := dyno.GetString(m, "extension", "cloud-context")
+		return &KubeconfigExtension{ClusterID: clusterID, CloudContext: cloudContext}, true
+	}
+	return nil, false
+}
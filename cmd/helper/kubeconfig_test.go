@@ -0,0 +1,188 @@
+package helper
+
+import (
+	"testing"
+
+	"github.com/metal-stack/metal-lib/auth"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+const pruneKubeconfig = `
+apiVersion: v1
+kind: Config
+current-context: gone
+clusters:
+- name: gone
+  cluster:
+    server: https://gone
+- name: alive
+  cluster:
+    server: https://alive
+- name: foreign
+  cluster:
+    server: https://foreign
+contexts:
+- name: gone
+  context:
+    cluster: gone
+    user: cloudctl
+    extensions:
+    - name: cloudctl
+      extension:
+        cluster-id: id-gone
+        cloud-context: prod
+- name: alive
+  context:
+    cluster: alive
+    user: cloudctl
+    extensions:
+    - name: cloudctl
+      extension:
+        cluster-id: id-alive
+        cloud-context: prod
+- name: other-cloud
+  context:
+    cluster: alive
+    user: cloudctl
+    extensions:
+    - name: cloudctl
+      extension:
+        cluster-id: id-other
+        cloud-context: staging
+- name: foreign
+  context:
+    cluster: foreign
+    user: someone
+`
+
+func TestPruneKubeconfig(t *testing.T) {
+	cfg := make(map[interface{}]interface{})
+	assert.NoError(t, yaml.Unmarshal([]byte(pruneKubeconfig), cfg))
+
+	pruned, err := PruneKubeconfig(cfg, "prod", ExistingClusters{IDs: map[string]bool{"id-alive": true}})
+	assert.NoError(t, err)
+	assert.Equal(t, []PrunedContext{{Name: "gone", ClusterName: "gone", ClusterID: "id-gone"}}, pruned)
+
+	var contexts, clusters []string
+	for _, c := range cfg["contexts"].([]interface{}) {
+		contexts = append(contexts, c.(map[string]interface{})["name"].(string))
+	}
+	for _, c := range cfg["clusters"].([]interface{}) {
+		clusters = append(clusters, c.(map[string]interface{})["name"].(string))
+	}
+	assert.Equal(t, []string{"alive", "other-cloud", "foreign"}, contexts)
+	assert.Equal(t, []string{"alive", "foreign"}, clusters)
+	assert.Equal(t, "", cfg["current-context"])
+}
+
+// legacyKubeconfig was merged by cloudctl before the contexts were marked with the cloudctl extension
+const legacyKubeconfig = `
+apiVersion: v1
+kind: Config
+current-context: shop
+clusters:
+- name: Shop Test
+  cluster:
+    server: https://api.Shop Test.p1.cloud.example.com
+- name: shop
+  cluster:
+    server: https://api.shop.p1.cloud.example.com
+- name: blog
+  cluster:
+    server: https://api.blog.p2.cloud.example.com:443
+- name: minikube
+  cluster:
+    server: https://192.168.49.2:8443
+- name: elsewhere
+  cluster:
+    server: https://api.elsewhere.p1.other.example.com
+contexts:
+- name: shop
+  context:
+    cluster: shop
+    user: cloudctl
+- name: blog
+  context:
+    cluster: blog
+    user: cloudctl
+- name: minikube
+  context:
+    cluster: minikube
+    user: minikube
+- name: elsewhere
+  context:
+    cluster: elsewhere
+    user: cloudctl
+- name: renamed
+  context:
+    cluster: Shop Test
+    user: cloudctl
+`
+
+func TestPruneKubeconfigLegacyContexts(t *testing.T) {
+	cfg := make(map[interface{}]interface{})
+	assert.NoError(t, yaml.Unmarshal([]byte(legacyKubeconfig), cfg))
+
+	pruned, err := PruneKubeconfig(cfg, "prod", ExistingClusters{
+		IDs:       map[string]bool{"id-blog": true},
+		Endpoints: map[string]bool{"api.blog.p2.cloud.example.com": true},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []PrunedContext{{Name: "shop", ClusterName: "shop", Server: "https://api.shop.p1.cloud.example.com"}}, pruned)
+
+	var contexts []string
+	for _, c := range cfg["contexts"].([]interface{}) {
+		contexts = append(contexts, c.(map[string]interface{})["name"].(string))
+	}
+	assert.Equal(t, []string{"blog", "minikube", "elsewhere", "renamed"}, contexts, "existing clusters, foreign domains and other naming are kept")
+	assert.Equal(t, "", cfg["current-context"])
+
+	cfg = make(map[interface{}]interface{})
+	assert.NoError(t, yaml.Unmarshal([]byte(legacyKubeconfig), cfg))
+	pruned, err = PruneKubeconfig(cfg, "prod", ExistingClusters{})
+	assert.NoError(t, err)
+	assert.Empty(t, pruned, "without existing clusters the domain of the cloud context is unknown")
+}
+
+func TestEnrichKubeconfigTplWithExecUser(t *testing.T) {
+	tpl := `
+apiVersion: v1
+kind: Config
+clusters:
+- name: mycluster
+  cluster:
+    server: https://mycluster
+`
+	execUser := &ExecCredentialUser{
+		Name:       "cloudctl-prod-exec",
+		APIVersion: "client.authentication.k8s.io/v1beta1",
+		Command:    "cloudctl",
+		Args:       []string{"kubeconfig", "exec-credential", "--context", "prod"},
+	}
+
+	raw, err := EnrichKubeconfigTpl(tpl, &auth.AuthContext{User: "static"}, execUser)
+	assert.NoError(t, err)
+
+	cfg := struct {
+		CurrentContext string `yaml:"current-context"`
+		Users          []struct {
+			Name string `yaml:"name"`
+			User struct {
+				Exec struct {
+					Command string   `yaml:"command"`
+					Args    []string `yaml:"args"`
+				} `yaml:"exec"`
+				AuthProvider interface{} `yaml:"auth-provider"`
+			} `yaml:"user"`
+		} `yaml:"users"`
+	}{}
+	assert.NoError(t, yaml.Unmarshal(raw, &cfg))
+
+	assert.Equal(t, "cloudctl-prod-exec@mycluster", cfg.CurrentContext)
+	assert.Len(t, cfg.Users, 1)
+	assert.Equal(t, "cloudctl-prod-exec", cfg.Users[0].Name)
+	assert.Equal(t, "cloudctl", cfg.Users[0].User.Exec.Command)
+	assert.Equal(t, execUser.Args, cfg.Users[0].User.Exec.Args)
+	assert.Nil(t, cfg.Users[0].User.AuthProvider)
+}
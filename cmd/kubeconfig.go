@@ -0,0 +1,168 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/fi-ts/cloudctl/pkg/api"
+	"github.com/metal-stack/metal-lib/auth"
+	"github.com/metal-stack/metal-lib/jwt/sec"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"golang.org/x/term"
+)
+
+const (
+	execCredentialKind       = "ExecCredential"
+	execCredentialAPIVersion = "client.authentication.k8s.io/v1beta1"
+	execInfoEnv              = "KUBERNETES_EXEC_INFO"
+
+	// execCredentialMinValidity is the minimum remaining lifetime of a token handed out to kubectl
+	execCredentialMinValidity = time.Minute
+)
+
+var execCredentialAPIVersions = []string{
+	"client.authentication.k8s.io/v1alpha1",
+	"client.authentication.k8s.io/v1beta1",
+	"client.authentication.k8s.io/v1",
+}
+
+// execCredential is the subset of the client.authentication.k8s.io ExecCredential used by cloudctl,
+// it is defined here to not depend on client-go.
+type execCredential struct {
+	APIVersion string                `json:"apiVersion"`
+	Kind       string                `json:"kind"`
+	Spec       execCredentialSpec    `json:"spec"`
+	Status     *execCredentialStatus `json:"status,omitempty"`
+}
+
+type execCredentialSpec struct {
+	Interactive bool `json:"interactive,omitempty"`
+}
+
+type execCredentialStatus struct {
+	ExpirationTimestamp *time.Time `json:"expirationTimestamp,omitempty"`
+	Token               string     `json:"token"`
+}
+
+func newKubeconfigCmd() *cobra.Command {
+	kubeconfigCmd := &cobra.Command{
+		Use:   "kubeconfig",
+		Short: "kubeconfig related helpers",
+	}
+
+	kubeconfigExecCredentialCmd := &cobra.Command{
+		Use:   "exec-credential",
+		Short: "print the token of the cloudctl context as ExecCredential for kubectl",
+		Long: `implements the client.authentication.k8s.io exec credential plugin protocol and returns the token of the cloudctl context.
+this command is referenced by kubeconfigs written with "cluster kubeconfig --exec-credential" and is not meant to be called directly.
+if the token is expired and kubectl runs interactively, the login is started.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return kubeconfigExecCredential()
+		},
+		PreRun: bindPFlags,
+	}
+	kubeconfigExecCredentialCmd.Flags().String("context", "", "the cloudctl context to take the token from, defaults to the current context.")
+
+	kubeconfigCmd.AddCommand(kubeconfigExecCredentialCmd)
+
+	return kubeconfigCmd
+}
+
+func kubeconfigExecCredential() error {
+	request, err := execCredentialRequest()
+	if err != nil {
+		return err
+	}
+
+	ctxs, err := api.GetContexts()
+	if err != nil {
+		return err
+	}
+	name := viper.GetString("context")
+	if name == "" {
+		name = ctxs.CurrentContext
+	}
+	ctx, ok := ctxs.Contexts[name]
+	if !ok {
+		return fmt.Errorf("cloudctl context %q not found", name)
+	}
+
+	kubeconfig := viper.GetString("kubeconfig")
+	contextName := api.FormatContextName(api.CloudContext, name)
+
+	token, expiry, err := contextToken(kubeconfig, contextName)
+	if err != nil || time.Until(expiry) < execCredentialMinValidity {
+		if !request.Spec.Interactive {
+			return fmt.Errorf("token of cloudctl context %q is missing or expired, please run \"cloudctl login\"", name)
+		}
+
+		// stdout is reserved for the exec credential, so the login talks to stderr
+		handler := auth.NewUpdateKubeConfigHandler(kubeconfig, os.Stderr, auth.WithContextName(contextName))
+		err = oidcLogin(ctx, handler, os.Stderr)
+		if err != nil {
+			return err
+		}
+
+		token, expiry, err = contextToken(kubeconfig, contextName)
+		if err != nil {
+			return err
+		}
+	}
+
+	expiry = expiry.UTC()
+	request.Status = &execCredentialStatus{
+		ExpirationTimestamp: &expiry,
+		Token:               token,
+	}
+
+	return json.NewEncoder(os.Stdout).Encode(request)
+}
+
+// execCredentialRequest returns the exec credential passed by kubectl, if kubectl did not pass one
+// the plugin is called manually and is interactive when running in a terminal.
+func execCredentialRequest() (*execCredential, error) {
+	info := os.Getenv(execInfoEnv)
+	if info == "" {
+		return &execCredential{
+			APIVersion: execCredentialAPIVersion,
+			Kind:       execCredentialKind,
+			Spec: execCredentialSpec{
+				Interactive: term.IsTerminal(int(os.Stdin.Fd())),
+			},
+		}, nil
+	}
+
+	var request execCredential
+	err := json.Unmarshal([]byte(info), &request)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse %s: %w", execInfoEnv, err)
+	}
+	if request.Kind != execCredentialKind {
+		return nil, fmt.Errorf("%s contains unexpected kind %q", execInfoEnv, request.Kind)
+	}
+	for _, v := range execCredentialAPIVersions {
+		if request.APIVersion == v {
+			return &request, nil
+		}
+	}
+	return nil, fmt.Errorf("%s contains unsupported apiVersion %q", execInfoEnv, request.APIVersion)
+}
+
+// contextToken returns the id token of the given kubeconfig context and its expiry.
+func contextToken(kubeconfig, contextName string) (string, time.Time, error) {
+	authContext, err := auth.GetAuthContext(kubeconfig, contextName)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	if !authContext.AuthProviderOidc {
+		return "", time.Time{}, fmt.Errorf("user %s has no oidc authProvider, check config", authContext.User)
+	}
+	_, claims, err := sec.ParseTokenUnvalidatedUnfiltered(authContext.IDToken)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return authContext.IDToken, time.Unix(claims.ExpiresAt, 0), nil
+}
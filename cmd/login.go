@@ -33,29 +33,7 @@ func newLoginCmd() *cobra.Command {
 				handler = auth.NewUpdateKubeConfigHandler(viper.GetString("kubeconfig"), console, auth.WithContextName(api.FormatContextName(api.CloudContext, cs.CurrentContext)))
 			}
 
-			scopes := auth.DexScopes
-			ctx := api.MustDefaultContext()
-			if ctx.IssuerType == "generic" {
-				scopes = auth.GenericScopes
-			} else if ctx.CustomScopes != "" {
-				cs := strings.Split(ctx.CustomScopes, ",")
-				for i := range cs {
-					cs[i] = strings.TrimSpace(cs[i])
-				}
-				scopes = cs
-			}
-
-			config := auth.Config{
-				ClientID:     ctx.ClientID,
-				ClientSecret: ctx.ClientSecret,
-				IssuerURL:    ctx.IssuerURL,
-				Scopes:       scopes,
-				TokenHandler: handler,
-				Console:      console,
-				Debug:        viper.GetBool("debug"),
-			}
-
-			return auth.OIDCFlow(config)
+			return oidcLogin(api.MustDefaultContext(), handler, console)
 		},
 		PreRun: bindPFlags,
 	}
@@ -63,6 +41,32 @@ func newLoginCmd() *cobra.Command {
 	return loginCmd
 }
 
+// oidcLogin runs the oidc flow against the issuer of the given context and passes the received token to the handler.
+func oidcLogin(ctx api.Context, handler auth.TokenHandlerFunc, console io.Writer) error {
+	scopes := auth.DexScopes
+	if ctx.IssuerType == "generic" {
+		scopes = auth.GenericScopes
+	} else if ctx.CustomScopes != "" {
+		cs := strings.Split(ctx.CustomScopes, ",")
+		for i := range cs {
+			cs[i] = strings.TrimSpace(cs[i])
+		}
+		scopes = cs
+	}
+
+	config := auth.Config{
+		ClientID:     ctx.ClientID,
+		ClientSecret: ctx.ClientSecret,
+		IssuerURL:    ctx.IssuerURL,
+		Scopes:       scopes,
+		TokenHandler: handler,
+		Console:      console,
+		Debug:        viper.GetBool("debug"),
+	}
+
+	return auth.OIDCFlow(config)
+}
+
 func printTokenHandler(tokenInfo auth.TokenInfo) error {
 
 	fmt.Println(tokenInfo.IDToken)
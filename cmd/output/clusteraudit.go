@@ -0,0 +1,172 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+const (
+	AuditRulePrivilegedAllowed          = "privileged-allowed"
+	AuditRuleAuditOff                   = "audit-off"
+	AuditRuleAutoUpdateKubernetesOff    = "autoupdate-kubernetes-off"
+	AuditRuleAutoUpdateMachineImagesOff = "autoupdate-machineimages-off"
+	AuditRuleKonnectivity               = "konnectivity"
+	AuditRuleFirewallControllerOutdated = "firewall-controller-outdated"
+	AuditRuleImageExpiration            = "image-expiration"
+	AuditRuleSingleWorkerProduction     = "single-worker-production"
+	sarifSchema                         = "https://json.schemastore.org/sarif-2.1.0.json"
+	sarifVersion                        = "2.1.0"
+)
+
+// ClusterAuditRule is a best practice setting clusters are checked against
+type ClusterAuditRule struct {
+	ID          string `json:"id" yaml:"id"`
+	Severity    string `json:"severity" yaml:"severity"`
+	Description string `json:"description" yaml:"description"`
+}
+
+// ClusterAuditRules contains all rules of the cluster audit
+var ClusterAuditRules = []ClusterAuditRule{
+	{ID: AuditRulePrivilegedAllowed, Severity: IssueSeverityCritical, Description: "privileged containers are allowed"},
+	{ID: AuditRuleAuditOff, Severity: IssueSeverityWarning, Description: "the kube-apiserver audit log is turned off"},
+	{ID: AuditRuleAutoUpdateKubernetesOff, Severity: IssueSeverityWarning, Description: "kubernetes patch versions are not updated automatically"},
+	{ID: AuditRuleAutoUpdateMachineImagesOff, Severity: IssueSeverityWarning, Description: "machine images are not updated automatically"},
+	{ID: AuditRuleKonnectivity, Severity: IssueSeverityWarning, Description: "konnectivity is used instead of reversed-vpn"},
+	{ID: AuditRuleFirewallControllerOutdated, Severity: IssueSeverityWarning, Description: "the firewall controller is older than the newest available version"},
+	{ID: AuditRuleImageExpiration, Severity: IssueSeverityWarning, Description: "machine images are expired or expire soon"},
+	{ID: AuditRuleSingleWorkerProduction, Severity: IssueSeverityCritical, Description: "a production cluster runs with a single worker"},
+}
+
+// ClusterAuditFinding is a best practice setting a cluster does not comply with
+type ClusterAuditFinding struct {
+	ClusterID   string `json:"cluster_id" yaml:"cluster_id"`
+	ClusterName string `json:"cluster_name" yaml:"cluster_name"`
+	ProjectID   string `json:"project_id" yaml:"project_id"`
+	Tenant      string `json:"tenant" yaml:"tenant"`
+	Rule        string `json:"rule" yaml:"rule"`
+	Severity    string `json:"severity" yaml:"severity"`
+	Message     string `json:"message" yaml:"message"`
+}
+
+// ClusterAuditFindings are the findings of the cluster audit
+type ClusterAuditFindings []ClusterAuditFinding
+
+// ClusterAuditTablePrinter prints the findings of the cluster audit in a table
+type ClusterAuditTablePrinter struct {
+	tablePrinter
+}
+
+func (s ClusterAuditTablePrinter) Print(data ClusterAuditFindings) {
+	s.wideHeader = []string{"UID", "Tenant", "Project", "Name", "Rule", "Severity", "Message"}
+	s.shortHeader = []string{"UID", "Name", "Rule", "Severity", "Message"}
+	for _, f := range data {
+		wide := []string{f.ClusterID, f.Tenant, f.ProjectID, f.ClusterName, f.Rule, f.Severity, f.Message}
+		short := []string{f.ClusterID, f.ClusterName, f.Rule, f.Severity, f.Message}
+		s.addWideData(wide, f)
+		s.addShortData(short, f)
+	}
+	s.render()
+}
+
+type (
+	sarifLog struct {
+		Schema  string     `json:"$schema"`
+		Version string     `json:"version"`
+		Runs    []sarifRun `json:"runs"`
+	}
+	sarifRun struct {
+		Tool    sarifTool     `json:"tool"`
+		Results []sarifResult `json:"results"`
+	}
+	sarifTool struct {
+		Driver sarifDriver `json:"driver"`
+	}
+	sarifDriver struct {
+		Name           string      `json:"name"`
+		Version        string      `json:"version,omitempty"`
+		InformationURI string      `json:"informationUri"`
+		Rules          []sarifRule `json:"rules"`
+	}
+	sarifRule struct {
+		ID                   string             `json:"id"`
+		ShortDescription     sarifMessage       `json:"shortDescription"`
+		DefaultConfiguration sarifConfiguration `json:"defaultConfiguration"`
+	}
+	sarifConfiguration struct {
+		Level string `json:"level"`
+	}
+	sarifMessage struct {
+		Text string `json:"text"`
+	}
+	sarifResult struct {
+		RuleID    string          `json:"ruleId"`
+		Level     string          `json:"level"`
+		Message   sarifMessage    `json:"message"`
+		Locations []sarifLocation `json:"locations"`
+	}
+	sarifLocation struct {
+		LogicalLocations []sarifLogicalLocation `json:"logicalLocations"`
+	}
+	sarifLogicalLocation struct {
+		Name               string `json:"name"`
+		FullyQualifiedName string `json:"fullyQualifiedName"`
+		Kind               string `json:"kind"`
+	}
+)
+
+// sarifLevel returns the sarif level of the given issue severity
+func sarifLevel(severity string) string {
+	if severity == IssueSeverityCritical {
+		return "error"
+	}
+	return "warning"
+}
+
+// ClusterAuditSARIF returns the findings of the cluster audit as SARIF log, which can be read by code scanning
+// and compliance tools. every cluster is reported as logical location <tenant>/<project>/<cluster id>.
+func ClusterAuditSARIF(tool, version string, findings ClusterAuditFindings) ([]byte, error) {
+	driver := sarifDriver{
+		Name:           tool,
+		Version:        version,
+		InformationURI: "https://github.com/fi-ts/cloudctl",
+	}
+	for _, r := range ClusterAuditRules {
+		driver.Rules = append(driver.Rules, sarifRule{
+			ID:                   r.ID,
+			ShortDescription:     sarifMessage{Text: r.Description},
+			DefaultConfiguration: sarifConfiguration{Level: sarifLevel(r.Severity)},
+		})
+	}
+
+	results := []sarifResult{}
+	for _, f := range findings {
+		results = append(results, sarifResult{
+			RuleID:  f.Rule,
+			Level:   sarifLevel(f.Severity),
+			Message: sarifMessage{Text: fmt.Sprintf("cluster %s: %s", f.ClusterName, f.Message)},
+			Locations: []sarifLocation{
+				{
+					LogicalLocations: []sarifLogicalLocation{
+						{
+							Name:               f.ClusterName,
+							FullyQualifiedName: f.Tenant + "/" + f.ProjectID + "/" + f.ClusterID,
+							Kind:               "resource",
+						},
+					},
+				},
+			},
+		})
+	}
+
+	log := sarifLog{
+		Schema:  sarifSchema,
+		Version: sarifVersion,
+		Runs: []sarifRun{
+			{
+				Tool:    sarifTool{Driver: driver},
+				Results: results,
+			},
+		},
+	}
+	return json.MarshalIndent(log, "", "    ")
+}
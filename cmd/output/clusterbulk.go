@@ -0,0 +1,37 @@
+package output
+
+import (
+	"github.com/fatih/color"
+)
+
+// ClusterBulkResult is the outcome of an operation on a single cluster of a bulk operation
+type ClusterBulkResult struct {
+	ClusterID   string `json:"cluster_id" yaml:"cluster_id"`
+	ClusterName string `json:"cluster_name" yaml:"cluster_name"`
+	ProjectID   string `json:"project_id" yaml:"project_id"`
+	Succeeded   bool   `json:"succeeded" yaml:"succeeded"`
+	Error       string `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// ClusterBulkResults are the outcomes of a bulk operation
+type ClusterBulkResults []ClusterBulkResult
+
+// ClusterBulkResultTablePrinter prints the summary of a bulk operation in a table
+type ClusterBulkResultTablePrinter struct {
+	tablePrinter
+}
+
+func (s ClusterBulkResultTablePrinter) Print(data ClusterBulkResults) {
+	s.wideHeader = []string{"UID", "Name", "Project", "Result", "Error"}
+	s.shortHeader = s.wideHeader
+	for _, r := range data {
+		result := color.GreenString("✔")
+		if !r.Succeeded {
+			result = color.RedString("✗")
+		}
+		row := []string{r.ClusterID, r.ClusterName, r.ProjectID, result, r.Error}
+		s.addWideData(row, r)
+		s.addShortData(row, r)
+	}
+	s.render()
+}
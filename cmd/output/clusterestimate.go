@@ -0,0 +1,57 @@
+package output
+
+import (
+	"fmt"
+)
+
+// ClusterCostEstimate contains the expected monthly costs of a cluster, the range is given by the minimum and maximum
+// size of its worker groups
+type ClusterCostEstimate struct {
+	ClusterName string                    `json:"cluster_name" yaml:"cluster_name"`
+	Items       []ClusterCostEstimateItem `json:"items" yaml:"items"`
+	MinMonthly  float64                   `json:"min_monthly" yaml:"min_monthly"`
+	MaxMonthly  float64                   `json:"max_monthly" yaml:"max_monthly"`
+}
+
+// ClusterCostEstimateItem contains the expected monthly costs of a part of a cluster
+type ClusterCostEstimateItem struct {
+	Item       string  `json:"item" yaml:"item"`
+	Type       string  `json:"type,omitempty" yaml:"type,omitempty"`
+	Min        int32   `json:"min" yaml:"min"`
+	Max        int32   `json:"max" yaml:"max"`
+	HourlyUnit float64 `json:"hourly_unit" yaml:"hourly_unit"`
+	MinMonthly float64 `json:"min_monthly" yaml:"min_monthly"`
+	MaxMonthly float64 `json:"max_monthly" yaml:"max_monthly"`
+}
+
+// ClusterCostEstimateTablePrinter prints the cost estimate of a cluster in a table
+type ClusterCostEstimateTablePrinter struct {
+	tablePrinter
+}
+
+func (s ClusterCostEstimateTablePrinter) Print(data *ClusterCostEstimate) {
+	s.wideHeader = []string{"Item", "Type", "Count", "Per Hour", "Per Month"}
+	s.shortHeader = []string{"Item", "Type", "Count", "Per Month"}
+
+	for _, i := range data.Items {
+		count := fmt.Sprintf("%d", i.Min)
+		if i.Max != i.Min {
+			count = fmt.Sprintf("%d-%d", i.Min, i.Max)
+		}
+		monthly := CostRange(i.MinMonthly, i.MaxMonthly)
+		s.addWideData([]string{i.Item, i.Type, count, fmt.Sprintf("%.2f €", i.HourlyUnit), monthly}, i)
+		s.addShortData([]string{i.Item, i.Type, count, monthly}, i)
+	}
+	total := CostRange(data.MinMonthly, data.MaxMonthly)
+	s.addWideData([]string{"Total", "", "", "", total}, data)
+	s.addShortData([]string{"Total", "", "", total}, data)
+	s.render()
+}
+
+// CostRange formats a range of costs, equal costs are shown only once
+func CostRange(min, max float64) string {
+	if min == max {
+		return fmt.Sprintf("%.2f €", min)
+	}
+	return fmt.Sprintf("%.2f € - %.2f €", min, max)
+}
@@ -0,0 +1,48 @@
+package output
+
+const (
+	UpgradeTypeCurrent = "current"
+	UpgradeTypePatch   = "patch"
+	UpgradeTypeMinor   = "minor"
+)
+
+// ClusterUpgradePlan contains the kubernetes versions a cluster can be upgraded to
+type ClusterUpgradePlan struct {
+	ClusterID      string                 `json:"cluster_id" yaml:"cluster_id"`
+	ClusterName    string                 `json:"cluster_name" yaml:"cluster_name"`
+	CurrentVersion string                 `json:"current_version" yaml:"current_version"`
+	ExpirationDate string                 `json:"expiration_date,omitempty" yaml:"expiration_date,omitempty"`
+	Targets        []ClusterUpgradeTarget `json:"targets" yaml:"targets"`
+}
+
+// ClusterUpgradeTarget is a kubernetes version a cluster can be upgraded to, minor upgrades have to be applied one after the other
+type ClusterUpgradeTarget struct {
+	Type    string `json:"type" yaml:"type"`
+	Version string `json:"version" yaml:"version"`
+}
+
+// ClusterUpgradePlanTablePrinter prints the upgrade plan of a cluster in a table
+type ClusterUpgradePlanTablePrinter struct {
+	tablePrinter
+}
+
+// Print prints the upgrade plan, the constraints contain no expiration dates for the target versions, so only the
+// expiration of the current version is shown next to it.
+func (s ClusterUpgradePlanTablePrinter) Print(data *ClusterUpgradePlan) {
+	s.wideHeader = []string{"Type", "Version"}
+	s.shortHeader = s.wideHeader
+
+	version := data.CurrentVersion
+	if data.ExpirationDate != "" {
+		version += " (expires " + data.ExpirationDate + ")"
+	}
+	current := []string{UpgradeTypeCurrent, version}
+	s.addWideData(current, data)
+	s.addShortData(current, data)
+	for _, t := range data.Targets {
+		row := []string{t.Type, t.Version}
+		s.addWideData(row, data)
+		s.addShortData(row, data)
+	}
+	s.render()
+}
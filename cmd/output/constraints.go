@@ -0,0 +1,184 @@
+package output
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/fatih/color"
+	"github.com/fi-ts/cloud-go/api/models"
+)
+
+type (
+	// ConstraintsTablePrinter prints the cluster constraints of a partition in one table per section,
+	// expiration dates are not printed as the api does not return them with the constraints
+	ConstraintsTablePrinter struct {
+		tablePrinter
+	}
+	// PartitionConstraintsTablePrinter prints which cluster constraints exist in which partition side by side
+	PartitionConstraintsTablePrinter struct {
+		tablePrinter
+	}
+	// PartitionConstraints contains the cluster constraints of several partitions
+	PartitionConstraints struct {
+		Partitions  []string                              `json:"partitions" yaml:"partitions"`
+		Constraints map[string]*models.V1ShootConstraints `json:"constraints" yaml:"constraints"`
+	}
+)
+
+// constraintKinds are the kinds of options of the cluster constraints in the order they are printed
+var constraintKinds = []string{"kubernetes", "machine-type", "machine-image", "firewall-type", "firewall-image", "firewall-controller", "network"}
+
+// constraintValue is a single option of the cluster constraints
+type constraintValue struct {
+	kind  string
+	value string
+}
+
+func (s ConstraintsTablePrinter) Print(data *models.V1ShootConstraints) {
+	if data == nil {
+		return
+	}
+
+	var rows [][]string
+	for _, v := range data.KubernetesVersions {
+		rows = append(rows, []string{v})
+	}
+	s.section([]string{"Kubernetes Version"}, nil, rows, data)
+
+	rows = nil
+	for _, t := range data.MachineTypes {
+		rows = append(rows, []string{t})
+	}
+	s.section([]string{"Machine Type"}, nil, rows, data)
+
+	rows = nil
+	for _, i := range data.MachineImages {
+		rows = append(rows, []string{strValue(i.Name), strValue(i.Version)})
+	}
+	s.section([]string{"Machine Image", "Version"}, nil, rows, data)
+
+	rows = nil
+	for _, t := range data.FirewallTypes {
+		rows = append(rows, []string{t})
+	}
+	s.section([]string{"Firewall Type"}, nil, rows, data)
+
+	rows = nil
+	for _, i := range data.FirewallImages {
+		rows = append(rows, []string{i})
+	}
+	s.section([]string{"Firewall Image"}, nil, rows, data)
+
+	rows = nil
+	var wideRows [][]string
+	for _, v := range data.FirewallControllerVersions {
+		row := []string{strValue(v.Version), strValue(v.Classification)}
+		rows = append(rows, row)
+		wideRows = append(wideRows, append(row, strValue(v.URL)))
+	}
+	s.section([]string{"Firewall Controller", "Classification"}, []string{"URL"}, rows, data, wideRows...)
+
+	rows = nil
+	for _, n := range data.Networks {
+		rows = append(rows, []string{n})
+	}
+	s.section([]string{"Network"}, nil, rows, data)
+
+	rows = nil
+	for _, p := range data.Partitions {
+		rows = append(rows, []string{p})
+	}
+	s.section([]string{"Partition"}, nil, rows, data)
+}
+
+// section renders a table of its own for a section of the constraints, sections without rows are skipped.
+// wide rows default to the short rows if none are given.
+func (s *ConstraintsTablePrinter) section(header, wideHeader []string, rows [][]string, data interface{}, wideRows ...[]string) {
+	if len(rows) == 0 {
+		return
+	}
+	if len(wideRows) == 0 {
+		wideRows = rows
+	}
+	s.shortHeader = header
+	s.wideHeader = append(append([]string{}, header...), wideHeader...)
+	s.shortData = [][]string{}
+	s.wideData = [][]string{}
+	for i := range rows {
+		s.addShortData(rows[i], data)
+		s.addWideData(wideRows[i], data)
+	}
+	s.render()
+	if s.template == nil {
+		fmt.Fprintln(s.outWriter)
+	}
+}
+
+func (s PartitionConstraintsTablePrinter) Print(data *PartitionConstraints) {
+	s.shortHeader = append([]string{"Type", "Value"}, data.Partitions...)
+	s.wideHeader = s.shortHeader
+
+	var values []constraintValue
+	available := map[constraintValue]map[string]bool{}
+	for _, p := range data.Partitions {
+		for _, v := range constraintValues(data.Constraints[p]) {
+			if _, ok := available[v]; !ok {
+				values = append(values, v)
+				available[v] = map[string]bool{}
+			}
+			available[v][p] = true
+		}
+	}
+
+	kindIndex := map[string]int{}
+	for i, k := range constraintKinds {
+		kindIndex[k] = i
+	}
+	sort.SliceStable(values, func(i, j int) bool {
+		return kindIndex[values[i].kind] < kindIndex[values[j].kind]
+	})
+
+	for _, v := range values {
+		row := []string{v.kind, v.value}
+		for _, p := range data.Partitions {
+			if available[v][p] {
+				row = append(row, color.GreenString("✔"))
+			} else {
+				row = append(row, "")
+			}
+		}
+		s.addWideData(row, data)
+		s.addShortData(row, data)
+	}
+	s.render()
+}
+
+// constraintValues returns all options of the given constraints grouped by their kind
+func constraintValues(c *models.V1ShootConstraints) []constraintValue {
+	if c == nil {
+		return nil
+	}
+	var result []constraintValue
+	for _, v := range c.KubernetesVersions {
+		result = append(result, constraintValue{kind: "kubernetes", value: v})
+	}
+	for _, t := range c.MachineTypes {
+		result = append(result, constraintValue{kind: "machine-type", value: t})
+	}
+	for _, i := range c.MachineImages {
+		result = append(result, constraintValue{kind: "machine-image", value: strValue(i.Name) + "-" + strValue(i.Version)})
+	}
+	for _, t := range c.FirewallTypes {
+		result = append(result, constraintValue{kind: "firewall-type", value: t})
+	}
+	for _, i := range c.FirewallImages {
+		result = append(result, constraintValue{kind: "firewall-image", value: i})
+	}
+	for _, v := range c.FirewallControllerVersions {
+		result = append(result, constraintValue{kind: "firewall-controller", value: strValue(v.Version)})
+	}
+	for _, n := range c.Networks {
+		result = append(result, constraintValue{kind: "network", value: n})
+	}
+	return result
+}
@@ -227,6 +227,18 @@ func (t tablePrinter) Print(data interface{}) error {
 		ShootLastErrorsTablePrinter{t}.Print(d)
 	case *models.V1beta1LastOperation:
 		ShootLastOperationTablePrinter{t}.Print(d)
+	case []*models.V1Worker:
+		WorkerGroupTablePrinter{t}.Print(d)
+	case []*models.V1EgressRule:
+		EgressRuleTablePrinter{t}.Print(d)
+	case ClusterAuditFindings:
+		ClusterAuditTablePrinter{t}.Print(d)
+	case ClusterBulkResults:
+		ClusterBulkResultTablePrinter{t}.Print(d)
+	case *ClusterCostEstimate:
+		ClusterCostEstimateTablePrinter{t}.Print(d)
+	case *ClusterUpgradePlan:
+		ClusterUpgradePlanTablePrinter{t}.Print(d)
 	case *models.V1ProjectResponse:
 		ProjectTablePrinter{t}.Print([]*models.V1ProjectResponse{d})
 	case []*models.V1ProjectResponse:
@@ -294,9 +306,11 @@ func (t tablePrinter) Print(data interface{}) error {
 			outWriter: t.outWriter,
 		}.Print(d)
 	case *cluster.ListConstraintsOK:
-		return yamlPrinter{
-			outWriter: t.outWriter,
-		}.Print(d)
+		ConstraintsTablePrinter{t}.Print(d.Payload)
+	case *models.V1ShootConstraints:
+		ConstraintsTablePrinter{t}.Print(d)
+	case *PartitionConstraints:
+		PartitionConstraintsTablePrinter{t}.Print(d)
 	default:
 		return fmt.Errorf("unknown table printer for type: %T", d)
 	}
@@ -0,0 +1,164 @@
+package output
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/fi-ts/cloud-go/api/models"
+	"github.com/spf13/viper"
+)
+
+const (
+	IssueSeverityWarning  = "warning"
+	IssueSeverityCritical = "critical"
+
+	IssueTypeImageExpiration      = "image-expiration"
+	IssueTypeKubernetesExpiration = "kubernetes-expiration"
+	IssueTypeFirewallCount        = "firewall-count"
+)
+
+// IssueSeverities contains all severities ordered by their urgency
+var IssueSeverities = []string{IssueSeverityWarning, IssueSeverityCritical}
+
+// ShootIssue is a problem of a cluster which requires an action of the user
+type ShootIssue struct {
+	ClusterID     string `json:"cluster_id" yaml:"cluster_id"`
+	ClusterName   string `json:"cluster_name" yaml:"cluster_name"`
+	Type          string `json:"type" yaml:"type"`
+	Severity      string `json:"severity" yaml:"severity"`
+	Machine       string `json:"machine,omitempty" yaml:"machine,omitempty"`
+	DaysRemaining *int   `json:"days_remaining,omitempty" yaml:"days_remaining,omitempty"`
+	Message       string `json:"message" yaml:"message"`
+}
+
+// IssueSeverityLevel returns the urgency of the given severity, higher is more urgent, -1 if the severity is unknown
+func IssueSeverityLevel(severity string) int {
+	for i, s := range IssueSeverities {
+		if s == severity {
+			return i
+		}
+	}
+	return -1
+}
+
+// ShootIssues returns all issues of the given cluster
+func ShootIssues(shoot *models.V1ClusterResponse) []ShootIssue {
+	var issues []ShootIssue
+
+	ms := shoot.Machines
+	ms = append(ms, shoot.Firewalls...)
+
+	for _, m := range ms {
+		issue := imageExpires(m)
+		if issue != nil {
+			issues = append(issues, *issue)
+		}
+	}
+
+	if shoot.Firewalls != nil {
+		switch len(shoot.Firewalls) {
+		case 0:
+			issues = append(issues, ShootIssue{
+				Type:     IssueTypeFirewallCount,
+				Severity: IssueSeverityCritical,
+				Message:  "Cluster has no firewall",
+			})
+		case 1:
+		default:
+			issues = append(issues, ShootIssue{
+				Type:     IssueTypeFirewallCount,
+				Severity: IssueSeverityWarning,
+				Message:  "Cluster has multiple firewalls, cluster requires manual administration",
+			})
+		}
+	}
+
+	issue := kubernetesExpires(shoot)
+	if issue != nil {
+		issues = append(issues, *issue)
+	}
+
+	for i := range issues {
+		issues[i].ClusterID = strValue(shoot.ID)
+		issues[i].ClusterName = strValue(shoot.Name)
+	}
+
+	return issues
+}
+
+func imageExpires(m *models.ModelsV1MachineResponse) *ShootIssue {
+	if m.Allocation == nil || m.Allocation.Image == nil || m.Allocation.Image.ExpirationDate == nil {
+		return nil
+	}
+
+	host := strValue(m.Allocation.Name)
+	imageID := strValue(m.Allocation.Image.ID)
+
+	t, err := time.Parse(time.RFC3339, *m.Allocation.Image.ExpirationDate)
+	if err != nil {
+		return &ShootIssue{
+			Type:     IssueTypeImageExpiration,
+			Severity: IssueSeverityWarning,
+			Machine:  host,
+			Message:  fmt.Sprintf("Image of %q has no valid expiration date: %s", host, imageID),
+		}
+	}
+
+	if t.IsZero() {
+		return nil
+	}
+
+	viper.SetDefault("image-expiration-warning-days", ImageExpirationDaysDefault)
+	expirationWarningDays := viper.GetInt("image-expiration-warning-days")
+	expiresInHours := int(time.Until(t).Hours())
+	days := expiresInHours / 24
+
+	if expiresInHours <= 0 {
+		return &ShootIssue{
+			Type:          IssueTypeImageExpiration,
+			Severity:      IssueSeverityCritical,
+			Machine:       host,
+			DaysRemaining: &days,
+			Message:       fmt.Sprintf("Image of %q has expired since %d day(s): %s", host, -days, imageID),
+		}
+	} else if expiresInHours < expirationWarningDays*24 {
+		return &ShootIssue{
+			Type:          IssueTypeImageExpiration,
+			Severity:      IssueSeverityWarning,
+			Machine:       host,
+			DaysRemaining: &days,
+			Message:       fmt.Sprintf("Image of %q expires in %d day(s): %s", host, days, imageID),
+		}
+	}
+
+	return nil
+}
+
+func kubernetesExpires(shoot *models.V1ClusterResponse) *ShootIssue {
+	if shoot.Kubernetes == nil || shoot.Kubernetes.ExpirationDate == nil || time.Time(*shoot.Kubernetes.ExpirationDate).IsZero() {
+		return nil
+	}
+
+	viper.SetDefault("kubernetes-expiration-warning-days", ImageExpirationDaysDefault)
+	expirationWarningDays := viper.GetInt("kubernetes-expiration-warning-days")
+	expiresInHours := int(time.Until(time.Time(*shoot.Kubernetes.ExpirationDate)).Hours())
+	days := expiresInHours / 24
+
+	if expiresInHours <= 0 {
+		return &ShootIssue{
+			Type:          IssueTypeKubernetesExpiration,
+			Severity:      IssueSeverityCritical,
+			DaysRemaining: &days,
+			Message:       fmt.Sprintf("Kubernetes support has expired since %d day(s): %s", -days, strValue(shoot.Kubernetes.Version)),
+		}
+	} else if expiresInHours < expirationWarningDays*24 {
+		return &ShootIssue{
+			Type:          IssueTypeKubernetesExpiration,
+			Severity:      IssueSeverityWarning,
+			DaysRemaining: &days,
+			Message:       fmt.Sprintf("Kubernetes support expires in %d day(s): %s", days, strValue(shoot.Kubernetes.Version)),
+		}
+	}
+
+	return nil
+}
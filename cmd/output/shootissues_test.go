@@ -0,0 +1,223 @@
+package output
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fi-ts/cloud-go/api/models"
+	"github.com/go-openapi/strfmt"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/utils/pointer"
+)
+
+func machineWithImageExpiring(name, expiration string) *models.ModelsV1MachineResponse {
+	return &models.ModelsV1MachineResponse{
+		Allocation: &models.ModelsV1MachineAllocation{
+			Name:  pointer.StringPtr(name),
+			Image: &models.ModelsV1ImageResponse{ID: pointer.StringPtr("ubuntu-20.04"), ExpirationDate: pointer.StringPtr(expiration)},
+		},
+	}
+}
+
+func Test_imageExpires(t *testing.T) {
+	in := func(d time.Duration) string {
+		return time.Now().Add(d).Format(time.RFC3339)
+	}
+	day := 24 * time.Hour
+
+	tests := []struct {
+		name         string
+		machine      *models.ModelsV1MachineResponse
+		wantSeverity string
+		wantDays     int
+		wantMessage  string
+	}{
+		{
+			name:    "no allocation",
+			machine: &models.ModelsV1MachineResponse{},
+		},
+		{
+			name:         "invalid expiration date",
+			machine:      machineWithImageExpiring("worker-1", "tomorrow"),
+			wantSeverity: IssueSeverityWarning,
+			wantMessage:  `Image of "worker-1" has no valid expiration date: ubuntu-20.04`,
+		},
+		{
+			name:    "expires after the warning period",
+			machine: machineWithImageExpiring("worker-1", in(ImageExpirationDaysDefault*day+time.Hour)),
+		},
+		{
+			name:         "expires at the end of the warning period",
+			machine:      machineWithImageExpiring("worker-1", in(ImageExpirationDaysDefault*day-time.Hour)),
+			wantSeverity: IssueSeverityWarning,
+			wantDays:     ImageExpirationDaysDefault - 1,
+			wantMessage:  `Image of "worker-1" expires in 13 day(s): ubuntu-20.04`,
+		},
+		{
+			name:         "expires within the next day",
+			machine:      machineWithImageExpiring("worker-1", in(90*time.Minute)),
+			wantSeverity: IssueSeverityWarning,
+			wantDays:     0,
+			wantMessage:  `Image of "worker-1" expires in 0 day(s): ubuntu-20.04`,
+		},
+		{
+			name:         "just expired",
+			machine:      machineWithImageExpiring("worker-1", in(-time.Hour)),
+			wantSeverity: IssueSeverityCritical,
+			wantDays:     0,
+			wantMessage:  `Image of "worker-1" has expired since 0 day(s): ubuntu-20.04`,
+		},
+		{
+			name:         "expired days ago",
+			machine:      machineWithImageExpiring("worker-1", in(-3*day-time.Hour)),
+			wantSeverity: IssueSeverityCritical,
+			wantDays:     -3,
+			wantMessage:  `Image of "worker-1" has expired since 3 day(s): ubuntu-20.04`,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			got := imageExpires(tt.machine)
+			if tt.wantSeverity == "" {
+				assert.Nil(t, got)
+				return
+			}
+			if assert.NotNil(t, got) {
+				assert.Equal(t, IssueTypeImageExpiration, got.Type)
+				assert.Equal(t, tt.wantSeverity, got.Severity)
+				assert.Equal(t, "worker-1", got.Machine)
+				assert.Equal(t, tt.wantMessage, got.Message)
+				if got.DaysRemaining != nil {
+					assert.Equal(t, tt.wantDays, *got.DaysRemaining)
+				}
+			}
+		})
+	}
+}
+
+func Test_kubernetesExpires(t *testing.T) {
+	in := func(d time.Duration) *strfmt.DateTime {
+		dt := strfmt.DateTime(time.Now().Add(d))
+		return &dt
+	}
+	day := 24 * time.Hour
+
+	tests := []struct {
+		name         string
+		expiration   *strfmt.DateTime
+		wantSeverity string
+		wantDays     int
+		wantMessage  string
+	}{
+		{
+			name: "no expiration",
+		},
+		{
+			name:       "zero expiration",
+			expiration: &strfmt.DateTime{},
+		},
+		{
+			name:       "expires after the warning period",
+			expiration: in(ImageExpirationDaysDefault*day + time.Hour),
+		},
+		{
+			name:         "expires at the end of the warning period",
+			expiration:   in(ImageExpirationDaysDefault*day - time.Hour),
+			wantSeverity: IssueSeverityWarning,
+			wantDays:     ImageExpirationDaysDefault - 1,
+			wantMessage:  "Kubernetes support expires in 13 day(s): 1.21.5",
+		},
+		{
+			name:         "just expired",
+			expiration:   in(-time.Hour),
+			wantSeverity: IssueSeverityCritical,
+			wantDays:     0,
+			wantMessage:  "Kubernetes support has expired since 0 day(s): 1.21.5",
+		},
+		{
+			name:         "expired days ago",
+			expiration:   in(-10*day - time.Hour),
+			wantSeverity: IssueSeverityCritical,
+			wantDays:     -10,
+			wantMessage:  "Kubernetes support has expired since 10 day(s): 1.21.5",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			got := kubernetesExpires(&models.V1ClusterResponse{
+				Kubernetes: &models.V1Kubernetes{Version: pointer.StringPtr("1.21.5"), ExpirationDate: tt.expiration},
+			})
+			if tt.wantSeverity == "" {
+				assert.Nil(t, got)
+				return
+			}
+			if assert.NotNil(t, got) {
+				assert.Equal(t, IssueTypeKubernetesExpiration, got.Type)
+				assert.Equal(t, tt.wantSeverity, got.Severity)
+				assert.Equal(t, &tt.wantDays, got.DaysRemaining)
+				assert.Equal(t, tt.wantMessage, got.Message)
+			}
+		})
+	}
+}
+
+func Test_ShootIssues(t *testing.T) {
+	expired := time.Now().Add(-48 * time.Hour).Format(time.RFC3339)
+
+	tests := []struct {
+		name      string
+		firewalls []*models.ModelsV1MachineResponse
+		machines  []*models.ModelsV1MachineResponse
+		want      []ShootIssue
+	}{
+		{
+			name: "firewalls not returned",
+		},
+		{
+			name:      "single firewall",
+			firewalls: []*models.ModelsV1MachineResponse{{}},
+		},
+		{
+			name:      "no firewall",
+			firewalls: []*models.ModelsV1MachineResponse{},
+			want: []ShootIssue{
+				{ClusterID: "c1", ClusterName: "shop", Type: IssueTypeFirewallCount, Severity: IssueSeverityCritical, Message: "Cluster has no firewall"},
+			},
+		},
+		{
+			name:      "multiple firewalls",
+			firewalls: []*models.ModelsV1MachineResponse{{}, {}},
+			want: []ShootIssue{
+				{ClusterID: "c1", ClusterName: "shop", Type: IssueTypeFirewallCount, Severity: IssueSeverityWarning, Message: "Cluster has multiple firewalls, cluster requires manual administration"},
+			},
+		},
+		{
+			name:      "expired images of workers and firewalls",
+			firewalls: []*models.ModelsV1MachineResponse{machineWithImageExpiring("firewall-1", expired)},
+			machines:  []*models.ModelsV1MachineResponse{machineWithImageExpiring("worker-1", expired)},
+			want: []ShootIssue{
+				{ClusterID: "c1", ClusterName: "shop", Type: IssueTypeImageExpiration, Severity: IssueSeverityCritical, Machine: "worker-1", DaysRemaining: pointer.IntPtr(-2), Message: `Image of "worker-1" has expired since 2 day(s): ubuntu-20.04`},
+				{ClusterID: "c1", ClusterName: "shop", Type: IssueTypeImageExpiration, Severity: IssueSeverityCritical, Machine: "firewall-1", DaysRemaining: pointer.IntPtr(-2), Message: `Image of "firewall-1" has expired since 2 day(s): ubuntu-20.04`},
+			},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			got := ShootIssues(&models.V1ClusterResponse{
+				ID:        pointer.StringPtr("c1"),
+				Name:      pointer.StringPtr("shop"),
+				Firewalls: tt.firewalls,
+				Machines:  tt.machines,
+			})
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_IssueSeverityLevel(t *testing.T) {
+	assert.Less(t, IssueSeverityLevel(IssueSeverityWarning), IssueSeverityLevel(IssueSeverityCritical))
+	assert.Equal(t, -1, IssueSeverityLevel("info"))
+}
@@ -8,7 +8,6 @@ import (
 	"github.com/fi-ts/cloud-go/api/models"
 	"github.com/fi-ts/cloudctl/cmd/helper"
 	"github.com/gardener/gardener/pkg/apis/core/v1beta1"
-	"github.com/spf13/viper"
 )
 
 type (
@@ -33,6 +32,14 @@ type (
 	ShootLastOperationTablePrinter struct {
 		tablePrinter
 	}
+	// WorkerGroupTablePrinter print the worker groups of a Shoot Cluster in a Table
+	WorkerGroupTablePrinter struct {
+		tablePrinter
+	}
+	// EgressRuleTablePrinter print the egress rules of a Shoot Cluster in a Table
+	EgressRuleTablePrinter struct {
+		tablePrinter
+	}
 )
 
 const (
@@ -98,6 +105,55 @@ func (s ShootLastOperationTablePrinter) Print(data *models.V1beta1LastOperation)
 	s.render()
 }
 
+func (s WorkerGroupTablePrinter) Print(data []*models.V1Worker) {
+	s.wideHeader = []string{"Name", "Machine Type", "Image", "Runtime", "Min", "Max", "Max Surge", "Max Unavailable", "Health Timeout", "Drain Timeout"}
+	s.shortHeader = []string{"Name", "Machine Type", "Image", "Runtime", "Min", "Max"}
+	for _, w := range data {
+		image := ""
+		if w.MachineImage != nil {
+			image = strValue(w.MachineImage.Name) + "-" + strValue(w.MachineImage.Version)
+		}
+		runtime := strValue(w.CRI)
+		if runtime == "" {
+			runtime = "docker"
+		}
+		min := ""
+		if w.Minimum != nil {
+			min = fmt.Sprintf("%d", *w.Minimum)
+		}
+		max := ""
+		if w.Maximum != nil {
+			max = fmt.Sprintf("%d", *w.Maximum)
+		}
+		healthTimeout := ""
+		if w.HealthTimeout != 0 {
+			healthTimeout = time.Duration(w.HealthTimeout).String()
+		}
+		drainTimeout := ""
+		if w.DrainTimeout != 0 {
+			drainTimeout = time.Duration(w.DrainTimeout).String()
+		}
+		short := []string{strValue(w.Name), strValue(w.MachineType), image, runtime, min, max}
+		wide := append(short, strValue(w.MaxSurge), strValue(w.MaxUnavailable), healthTimeout, drainTimeout)
+		s.addWideData(wide, w)
+		s.addShortData(short, w)
+	}
+	s.render()
+}
+
+func (s EgressRuleTablePrinter) Print(data []*models.V1EgressRule) {
+	s.wideHeader = []string{"Network", "IP"}
+	s.shortHeader = s.wideHeader
+	for _, r := range data {
+		for _, ip := range r.IPs {
+			row := []string{strValue(r.NetworkID), ip}
+			s.addWideData(row, r)
+			s.addShortData(row, r)
+		}
+	}
+	s.render()
+}
+
 // Print a Shoot as table
 func (s ShootTablePrinter) Print(data []*models.V1ClusterResponse) {
 	s.wideHeader = []string{"UID", "Name", "Version", "Partition", "Domain", "Operation", "Progress", "Api", "Control", "Nodes", "System", "Size", "Age", "Purpose", "Privileged", "Audit", "Runtime", "Firewall", "Firewall Controller", "Egress IPs"}
@@ -148,30 +204,8 @@ func shootData(shoot *models.V1ClusterResponse, withIssues bool) ([]string, []st
 
 	maintainEmoji := ""
 	var issues []string
-
-	ms := shoot.Machines
-	ms = append(ms, shoot.Firewalls...)
-
-	for _, m := range ms {
-		expires := imageExpires(m)
-		if expires != nil {
-			issues = append(issues, expires.Error())
-		}
-	}
-
-	if shoot.Firewalls != nil {
-		switch len(shoot.Firewalls) {
-		case 0:
-			issues = append(issues, "Cluster has no firewall")
-		case 1:
-		default:
-			issues = append(issues, "Cluster has multiple firewalls, cluster requires manual administration")
-		}
-	}
-
-	expires := kubernetesExpires(shoot)
-	if expires != nil {
-		issues = append(issues, expires.Error())
+	for _, issue := range ShootIssues(shoot) {
+		issues = append(issues, fmt.Sprintf("[%s] %s", issue.Severity, issue.Message))
 	}
 
 	if len(issues) > 0 {
@@ -335,51 +369,3 @@ func newShootStats(status *models.V1beta1ShootStatus) *shootStats {
 	}
 	return &res
 }
-
-func imageExpires(m *models.ModelsV1MachineResponse) error {
-	if m.Allocation == nil || m.Allocation.Image == nil || m.Allocation.Image.ExpirationDate == nil {
-		return nil
-	}
-
-	host := *m.Allocation.Name
-	imageID := *m.Allocation.Image.ID
-
-	t, err := time.Parse(time.RFC3339, *m.Allocation.Image.ExpirationDate)
-	if err != nil {
-		return fmt.Errorf("Image of %q has no valid expiration date: %s", host, imageID)
-	}
-
-	if t.IsZero() {
-		return nil
-	}
-
-	viper.SetDefault("image-expiration-warning-days", ImageExpirationDaysDefault)
-	expirationWarningDays := viper.GetInt("image-expiration-warning-days")
-	expiresInHours := int(time.Until(t).Hours())
-
-	if expiresInHours <= 0 {
-		return fmt.Errorf("Image of %q has expired since %d day(s): %s", host, -expiresInHours/24, imageID)
-	} else if expiresInHours < expirationWarningDays*24 {
-		return fmt.Errorf("Image of %q expires in %d day(s): %s", host, expiresInHours/24, imageID)
-	}
-
-	return nil
-}
-
-func kubernetesExpires(shoot *models.V1ClusterResponse) error {
-	if shoot.Kubernetes == nil || shoot.Kubernetes.ExpirationDate == nil || time.Time(*shoot.Kubernetes.ExpirationDate).IsZero() {
-		return nil
-	}
-
-	viper.SetDefault("kubernetes-expiration-warning-days", ImageExpirationDaysDefault)
-	expirationWarningDays := viper.GetInt("kubernetes-expiration-warning-days")
-	expiresInHours := int(time.Until(time.Time(*shoot.Kubernetes.ExpirationDate)).Hours())
-
-	if expiresInHours <= 0 {
-		return fmt.Errorf("Kubernetes support has expired since %d day(s): %s", -expiresInHours/24, *shoot.Kubernetes.Version)
-	} else if expiresInHours < expirationWarningDays*24 {
-		return fmt.Errorf("Kubernetes support expires in %d day(s): %s", expiresInHours/24, *shoot.Kubernetes.Version)
-	}
-
-	return nil
-}
@@ -62,6 +62,7 @@ func newRootCmd() *cobra.Command {
 	rootCmd.AddCommand(newUpdateCmd(name))
 	rootCmd.AddCommand(newLoginCmd())
 	rootCmd.AddCommand(newWhoamiCmd())
+	rootCmd.AddCommand(newKubeconfigCmd())
 	rootCmd.AddCommand(newProjectCmd(cfg))
 	rootCmd.AddCommand(newTenantCmd(cfg))
 	rootCmd.AddCommand(newContextCmd(cfg))
@@ -85,10 +86,28 @@ func Execute() {
 			st := errors.WithStack(err)
 			fmt.Printf("%+v", st)
 		}
+		var exitErr *exitCodeError
+		if errors.As(err, &exitErr) {
+			os.Exit(exitErr.code)
+		}
 		os.Exit(1)
 	}
 }
 
+// exitCodeError is returned by commands which need to exit with a specific code, e.g. for monitoring
+type exitCodeError struct {
+	code int
+	err  error
+}
+
+func (e *exitCodeError) Error() string {
+	return e.err.Error()
+}
+
+func (e *exitCodeError) Unwrap() error {
+	return e.err
+}
+
 type config struct {
 	name        string
 	cloud       *client.CloudAPI
@@ -25,6 +25,8 @@ type Context struct {
 	ClientID     string  `yaml:"client_id"`
 	ClientSecret string  `yaml:"client_secret"`
 	HMAC         *string `yaml:"hmac"`
+	// Policy is the path to a policy file clusters are checked against before they are created or updated
+	Policy string `yaml:"policy"`
 }
 
 var defaultCtx = Context{